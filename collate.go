@@ -0,0 +1,207 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 半角カタカナ（U+FF66〜U+FF9D）に対応する全角カタカナ
+const halfwidthKatakana = "ヲァィゥェォャュョッーアイウエオカキクケコサシスセソタチツテトナニヌネノハヒフヘホマミムメモヤユヨラリルレロワン"
+
+// 濁点・半濁点の種類（基本の文字が同じときにこの順で並べる）
+const (
+	markNone = iota
+	markVoiced
+	markSemiVoiced
+)
+
+// kanaMark は濁音・半濁音を清音と濁点・半濁点の種類に分けたもの
+type kanaMark struct {
+	base rune
+	mark int
+}
+
+// voicedKana は濁音・半濁音のひらがなから清音への対応
+var voicedKana = map[rune]kanaMark{}
+
+var halfwidthKatakanaRunes = []rune(halfwidthKatakana)
+
+func init() {
+	bases := []rune("かきくけこさしすせそたちつてとはひふへほう")
+	for i, r := range []rune("がぎぐげござじずぜぞだぢづでどばびぶべぼゔ") {
+		voicedKana[r] = kanaMark{bases[i], markVoiced}
+	}
+	bases = []rune("はひふへほ")
+	for i, r := range []rune("ぱぴぷぺぽ") {
+		voicedKana[r] = kanaMark{bases[i], markSemiVoiced}
+	}
+}
+
+// compareNames はファイル名を人間向けの順序で比較する
+//   - 数字の並びは数値として比較（file2 < file10）
+//   - 大文字小文字、全角半角英数字、半角全角カタカナ、ひらがなカタカナを同一視
+//   - 濁点・半濁点はまず無視して比較し（か = が）、それ以外が同じなら清音、濁音、半濁音の順
+//
+// 漢字は読みが分からないためコードポイント順
+// 並べ替えで何度も呼ばれるので割り当てをしない
+func compareNames(a, b string) int {
+	if a == b {
+		return 0
+	}
+	origA, origB := a, b
+	marks := 0 // 最初に見つかった濁点・半濁点の違い
+
+	for a != "" && b != "" {
+		if ca, cb := a[0], b[0]; ca < utf8.RuneSelf && cb < utf8.RuneSelf && !isDigit(rune(ca)) && !isDigit(rune(cb)) {
+			// ASCII 同士はデコードせずに比較
+			if ka, kb := lowerASCII(ca), lowerASCII(cb); ka != kb {
+				return int(ka) - int(kb)
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		ra, _ := utf8.DecodeRuneInString(a)
+		rb, _ := utf8.DecodeRuneInString(b)
+		if isDigit(ra) && isDigit(rb) {
+			// 数字の並びを切り出して数値比較
+			na, nb := digitRun(a), digitRun(b)
+			if c := compareDigits(a[:na], b[:nb]); c != 0 {
+				return c
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+
+		ka, ma, na := nextCollationKey(a)
+		kb, mb, nb := nextCollationKey(b)
+		if ka != kb {
+			if ka < kb {
+				return -1
+			}
+			return 1
+		}
+		if marks == 0 {
+			marks = ma - mb
+		}
+		a, b = a[na:], b[nb:]
+	}
+
+	switch {
+	case a != "":
+		return 1
+	case b != "":
+		return -1
+	case marks != 0:
+		return marks
+	}
+
+	// 同一視した結果が同じなら元の文字列で決める（順序を安定させる）
+	return strings.Compare(origA, origB)
+}
+
+// nextCollationKey は s の先頭の1文字を比較用の文字と濁点・半濁点の種類にし、読んだバイト数を返す
+// 後ろに結合文字や半角の濁点・半濁点が続いていれば（macOS のファイル名や半角カナ）まとめて読む
+func nextCollationKey(s string) (rune, int, int) {
+	r, size := utf8.DecodeRuneInString(s)
+	key, mark := collationKey(r)
+	if next, n := utf8.DecodeRuneInString(s[size:]); n > 0 && mark == markNone && isHiragana(key) {
+		switch next {
+		case '゙', 'ﾞ':
+			mark, size = markVoiced, size+n
+		case '゚', 'ﾟ':
+			mark, size = markSemiVoiced, size+n
+		}
+	}
+	return key, mark, size
+}
+
+// digitRun は s の先頭に続く数字のバイト数
+func digitRun(s string) int {
+	n := 0
+	for _, r := range s {
+		if !isDigit(r) {
+			break
+		}
+		n += utf8.RuneLen(r)
+	}
+	return n
+}
+
+// compareDigits は数字列を数値として比較（先頭の0は無視）
+func compareDigits(a, b string) int {
+	a, b = trimZeros(a), trimZeros(b)
+	if na, nb := utf8.RuneCountInString(a), utf8.RuneCountInString(b); na != nb {
+		return na - nb
+	}
+	for a != "" {
+		ra, sa := utf8.DecodeRuneInString(a)
+		rb, sb := utf8.DecodeRuneInString(b)
+		// 全角と半角の数字は同じ値として比較
+		if da, db := digitValue(ra), digitValue(rb); da != db {
+			return da - db
+		}
+		a, b = a[sa:], b[sb:]
+	}
+	return 0
+}
+
+func trimZeros(digits string) string {
+	for {
+		r, size := utf8.DecodeRuneInString(digits)
+		if size == len(digits) || digitValue(r) != 0 {
+			return digits
+		}
+		digits = digits[size:]
+	}
+}
+
+// isDigit は半角/全角の数字か
+func isDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= '０' && r <= '９')
+}
+
+func digitValue(r rune) int {
+	if r >= '０' && r <= '９' {
+		return int(r - '０')
+	}
+	return int(r - '0')
+}
+
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func isHiragana(r rune) bool {
+	return r >= 'ぁ' && r <= 'ゖ'
+}
+
+// collationKey は比較用に文字を正規化し、濁点・半濁点の種類を返す
+func collationKey(r rune) (rune, int) {
+	if r < utf8.RuneSelf {
+		return rune(lowerASCII(byte(r))), markNone
+	}
+	switch {
+	case r >= '！' && r <= '～':
+		// 全角英数記号 → 半角
+		r = r - '！' + '!'
+	case r >= 'ｦ' && r <= 'ﾝ':
+		// 半角カタカナ → 全角カタカナ
+		r = halfwidthKatakanaRunes[r-'ｦ']
+	}
+	if r >= 'ァ' && r <= 'ヶ' {
+		// カタカナ → ひらがな（ヵヶは対応するひらがながある範囲）
+		r = r - 'ァ' + 'ぁ'
+	}
+	if isHiragana(r) {
+		if v, ok := voicedKana[r]; ok {
+			return v.base, v.mark
+		}
+		return r, markNone
+	}
+	return unicode.ToLower(r), markNone
+}
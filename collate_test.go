@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCompareNames(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // 符号だけ比較
+	}{
+		{"file2", "file10", -1},
+		{"file10", "file2", 1},
+		{"file007", "file7", -1}, // 数値が同じなら元の文字列で決める
+		{"file１", "file2", -1},   // 全角数字も数値として比較
+		{"file１０", "file9", 1},
+		{"file２", "file2", 1},
+		{"File.txt", "file.txt", -1},
+		{"README", "main.go", 1}, // 大文字小文字を同一視
+		{"Ａpple", "banana", -1},  // 全角英字は半角と同じ位置
+		{"カメラ", "きつね", -1},       // カタカナはひらがなと同じ並び
+		{"いぬ", "イヌ", -1},
+		{"かき", "がか", 1}, // 濁点はまず無視して比較（か = が）
+		{"か", "が", -1},  // それ以外が同じなら清音、濁音、半濁音の順
+		{"はな", "ばな", -1},
+		{"ばな", "ぱな", -1},
+		{"ｱ", "イ", -1}, // 半角カタカナは全角と同じ位置
+		{"ｶﾞｸ", "がく", 1},
+		{"ｶﾞｸ", "かし", -1}, // 半角の濁点も前の文字に付く
+		{"がき", "かき", 1},  // 結合文字の濁点（macOS のファイル名）
+		{"がき", "がく", -1},
+		{"ｦ", "ン", -1},
+		{"a", "a", 0},
+	}
+
+	for _, tt := range tests {
+		got := compareNames(tt.a, tt.b)
+		if sign(got) != tt.want {
+			t.Errorf("compareNames(%q, %q) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+		}
+		if back := compareNames(tt.b, tt.a); sign(back) != -tt.want {
+			t.Errorf("compareNames(%q, %q) = %d, want sign %d", tt.b, tt.a, back, -tt.want)
+		}
+	}
+}
+
+func TestCompareNamesSortsNaturally(t *testing.T) {
+	names := []string{"img10.png", "img２.png", "Img1.png", "img０３.png", "img20.png"}
+	want := []string{"Img1.png", "img２.png", "img０３.png", "img10.png", "img20.png"}
+
+	sort.Slice(names, func(i, j int) bool { return compareNames(names[i], names[j]) < 0 })
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("sorted = %v, want %v", names, want)
+		}
+	}
+}
+
+func TestHalfwidthKatakanaTable(t *testing.T) {
+	// U+FF66〜U+FF9D の56文字に対応している
+	if n := len(halfwidthKatakanaRunes); n != 'ﾝ'-'ｦ'+1 {
+		t.Fatalf("halfwidthKatakana has %d runes, want %d", n, 'ﾝ'-'ｦ'+1)
+	}
+	for _, pair := range []string{"ｦヲ", "ｱア", "ｰー", "ﾝン", "ﾀタ"} {
+		r := []rune(pair)
+		if got := halfwidthKatakanaRunes[r[0]-'ｦ']; got != r[1] {
+			t.Errorf("%c maps to %c, want %c", r[0], got, r[1])
+		}
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
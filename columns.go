@@ -0,0 +1,157 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	parentColumnRatio  = 20 // 親ディレクトリの列の幅（%）
+	previewColumnRatio = 40 // プレビューの列の幅（%）
+)
+
+// toggleColumns は3列表示（親ディレクトリ、現在のディレクトリ、プレビュー）を切り替える
+// 3列表示では中央の列に現在のディレクトリの直下だけを出す
+func (m *Model) toggleColumns() {
+	m.columns = !m.columns
+	m.tree = false
+	if m.columns {
+		m.loadParent()
+	} else if m.shallow {
+		// 3列表示中に移動したディレクトリは直下しか走査していないので読み直す
+		if entries, err := ScanFiles(m.currentDir, m.config); err == nil {
+			m.allEntries = entries
+			m.shallow = false
+		}
+	}
+	m.refreshKeepingCursor()
+}
+
+// loadParent は親ディレクトリの一覧を読み込む（ルートなら空）
+func (m *Model) loadParent() {
+	m.parentEntries = nil
+	parent := filepath.Dir(m.currentDir)
+	if parent == m.currentDir {
+		return
+	}
+
+	dirEntries, err := os.ReadDir(parent)
+	if err != nil {
+		return
+	}
+	for _, d := range dirEntries {
+		// 走査時と同じく隠しファイルと除外パターンは出さない
+		if strings.HasPrefix(d.Name(), ".") || shouldExclude(d.Name(), m.config.ExcludePatterns) {
+			continue
+		}
+		m.parentEntries = append(m.parentEntries, FileEntry{Path: d.Name(), Name: d.Name(), IsDir: d.IsDir(), Mode: d.Type()})
+	}
+	sort.SliceStable(m.parentEntries, func(i, j int) bool {
+		a, b := m.parentEntries[i], m.parentEntries[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		return compareNames(a.Name, b.Name) < 0
+	})
+}
+
+// goParent は親ディレクトリへ移動し、元のディレクトリにカーソルを置く
+func (m *Model) goParent() error {
+	parent := filepath.Dir(m.currentDir)
+	if parent == m.currentDir {
+		return nil
+	}
+	child := filepath.Base(m.currentDir)
+	if err := m.changeDirectory(parent); err != nil {
+		return err
+	}
+	for i, scored := range m.filteredEntries {
+		if scored.Entry.Path == child {
+			m.moveCursorTo(i)
+			break
+		}
+	}
+	return nil
+}
+
+// goChild はカーソルのディレクトリへ移動する（ファイルなら何もしない）
+func (m *Model) goChild() error {
+	if len(m.filteredEntries) == 0 {
+		return nil
+	}
+	entry := m.filteredEntries[m.cursor].Entry
+	if !entry.IsDir {
+		return nil
+	}
+	return m.changeDirectory(entry.Path)
+}
+
+// directChildren は現在のディレクトリ直下のエントリだけを返す
+func directChildren(entries []FileEntry) []FileEntry {
+	var children []FileEntry
+	for _, entry := range entries {
+		if entry.DirPath == "." {
+			children = append(children, entry)
+		}
+	}
+	return children
+}
+
+// parentWidth は親ディレクトリの列の幅（右の区切り線を含む。3列表示でなければ0）
+func (m *Model) parentWidth() int {
+	if !m.columns || m.width < minPreviewWidth {
+		return 0
+	}
+	return m.width * parentColumnRatio / 100
+}
+
+// parentWindow は親ディレクトリの列で強調するエントリと、height 行に収めるための表示開始位置
+// 現在のディレクトリが真ん中あたりに見えるようにずらす
+// 現在のディレクトリが隠しディレクトリや除外対象で一覧にないときは -1 で、先頭から出す
+func (m *Model) parentWindow(height int) (int, int) {
+	current := filepath.Base(m.currentDir)
+	selected := -1
+	for i, entry := range m.parentEntries {
+		if entry.Name == current {
+			selected = i
+			break
+		}
+	}
+	if selected < 0 {
+		return -1, 0
+	}
+	return selected, max(0, min(selected-height/2, len(m.parentEntries)-height))
+}
+
+// renderParent は親ディレクトリの列を height 行で描画（現在のディレクトリを反転表示）
+func (m *Model) renderParent(width, height int) []string {
+	selected, offset := m.parentWindow(height)
+	lines := make([]string, height)
+	for row := range lines {
+		i := offset + row
+		if i >= len(m.parentEntries) {
+			break
+		}
+		entry := m.parentEntries[i]
+		name := truncateWidth(m.icons.Icon(entry)+entry.Name, width-1)
+		style := m.entryStyle(entry)
+		if i == selected {
+			lines[row] = paint(m.colors.Cursor, ">") + paint(strings.TrimPrefix(style+";7", ";"), name)
+		} else {
+			lines[row] = " " + paint(style, name)
+		}
+	}
+	return lines
+}
+
+// parentAtRow は親ディレクトリの列の row 行目のディレクトリ（絶対パス）
+func (m *Model) parentAtRow(row int) (string, bool) {
+	_, offset := m.parentWindow(m.bodyHeight())
+	i := offset + row
+	if i >= len(m.parentEntries) || !m.parentEntries[i].IsDir {
+		return "", false
+	}
+	return filepath.Join(filepath.Dir(m.currentDir), m.parentEntries[i].Name), true
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParentWindow(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{".config", "alpha", "beta", "gamma"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		current  string
+		selected int
+	}{
+		{"beta", 1},
+		{".config", -1}, // 隠しディレクトリは親の一覧に出ない
+	}
+
+	for _, tt := range tests {
+		m := newTestModel()
+		m.currentDir = filepath.Join(root, tt.current)
+		m.columns = true
+		m.loadParent()
+
+		selected, offset := m.parentWindow(10)
+		if selected != tt.selected || offset != 0 {
+			t.Errorf("%s: parentWindow = %d, %d; want %d, 0", tt.current, selected, offset, tt.selected)
+		}
+
+		// 一覧にないときは無関係なディレクトリを強調しない
+		highlighted := 0
+		for _, line := range m.renderParent(20, 10) {
+			if strings.HasPrefix(line, paint(m.colors.Cursor, ">")) {
+				highlighted++
+			}
+		}
+		if want := min(1, tt.selected+1); highlighted != want {
+			t.Errorf("%s: %d highlighted rows, want %d", tt.current, highlighted, want)
+		}
+	}
+}
+
+func TestColumnsFilterDirectChildren(t *testing.T) {
+	m := newTestModel("cmd/", "cmd/app/", "cmd/app/main.go", "main.go", "README.md")
+	m.columns = true
+	m.updateFilter()
+	if got := strings.Join(m.paths(), " "); got != "cmd main.go README.md" {
+		t.Errorf("columns list = %q, want only direct children", got)
+	}
+
+	m.query = "main"
+	m.updateFilter()
+	if got := strings.Join(m.paths(), " "); got != "main.go" {
+		t.Errorf("filtered columns list = %q, want main.go", got)
+	}
+}
+
+func TestGoParentScansOneLevel(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b", "c"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"top.txt", "a/b/c/deep.txt"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := newTestModel()
+	m.currentDir = filepath.Join(root, "a")
+	m.toggleColumns()
+	if err := m.goParent(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3列表示では親ディレクトリの直下だけを読む
+	var paths []string
+	for _, entry := range m.allEntries {
+		paths = append(paths, entry.Path)
+	}
+	if got := strings.Join(paths, " "); got != "a top.txt" {
+		t.Errorf("allEntries = %s, want a top.txt", got)
+	}
+	if got := m.filteredEntries[m.cursor].Entry.Path; got != "a" {
+		t.Errorf("cursor is on %s, want a", got)
+	}
+
+	// 3列表示をやめると配下をすべて読み直す
+	m.toggleColumns()
+	if !slices.ContainsFunc(m.allEntries, func(e FileEntry) bool { return e.Path == filepath.Join("a", "b", "c", "deep.txt") }) {
+		t.Errorf("allEntries after leaving columns mode = %v, want the whole tree", m.allEntries)
+	}
+}
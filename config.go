@@ -2,18 +2,126 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // Config はアプリケーション設定
 type Config struct {
-	ExcludePatterns []string `json:"exclude_patterns"` // 除外パターン
-	MaxDepth        int      `json:"max_depth"`        // 最大探索深度
-	MaxFiles        int      `json:"max_files"`        // 最大ファイル数
-	EnablePreview   bool     `json:"enable_preview"`   // プレビュー有効化
-	PreviewLines    int      `json:"preview_lines"`    // プレビュー行数
+	ExcludePatterns []string            `json:"exclude_patterns"` // 除外パターン
+	MaxDepth        int                 `json:"max_depth"`        // 最大探索深度
+	MaxFiles        int                 `json:"max_files"`        // 最大ファイル数
+	EnablePreview   bool                `json:"enable_preview"`   // プレビュー有効化
+	PreviewLines    int                 `json:"preview_lines"`    // プレビュー行数
+	ScrollOff       int                 `json:"scroll_off"`       // カーソル上下に残す行数
+	SyncOutput      string              `json:"sync_output"`      // 同期出力モード: auto/on/off
+	Ranking         RankingConfig       `json:"ranking"`          // スコアリング設定
+	Layout          LayoutConfig        `json:"layout"`           // 画面レイアウト
+	Theme           string              `json:"theme"`            // 配色: dark/light/high-contrast
+	Colors          Theme               `json:"colors"`           // テーマの一部の色を上書き
+	LSColors        bool                `json:"ls_colors"`        // LS_COLORS でエントリを色分けする
+	Icons           IconConfig          `json:"icons"`            // アイコン
+	Mouse           bool                `json:"mouse"`            // マウス操作を有効にする
+	Keys            map[string][]string `json:"keys"`             // キーバインドの上書き（アクション名 → キー名）
+}
+
+// RankingConfig はスコアリングの重み
+type RankingConfig struct {
+	ExactDir          int      `json:"exact_dir"`           // ディレクトリ名完全一致
+	Prefix            int      `json:"prefix"`              // ファイル名前方一致
+	PrefixDirBonus    int      `json:"prefix_dir_bonus"`    // 前方一致がディレクトリのときの加点
+	Substring         int      `json:"substring"`           // ファイル名部分一致
+	SubstringPosition int      `json:"substring_position"`  // 部分一致の位置1文字ごとの減点
+	SubstringDirBonus int      `json:"substring_dir_bonus"` // 部分一致がディレクトリのときの加点
+	Fuzzy             int      `json:"fuzzy"`               // ファイル名曖昧一致（部分列）
+	ParentDir         int      `json:"parent_dir"`          // 親ディレクトリ名一致
+	ParentDepth       int      `json:"parent_depth"`        // 親ディレクトリが1階層上がるごとの減点
+	BoundaryBonus     int      `json:"boundary_bonus"`      // 区切り文字（_ - . /）直後の一致
+	CamelCaseBonus    int      `json:"camel_case_bonus"`    // camelCase の山（大文字）での一致
+	ConsecutiveBonus  int      `json:"consecutive_bonus"`   // 連続した文字の一致
+	TieBreakers       []string `json:"tie_breakers"`        // 同点時の並び順（先頭から優先）
+}
+
+// LayoutConfig は画面レイアウト
+type LayoutConfig struct {
+	PreviewPosition string `json:"preview_position"` // プレビューの位置: right/left/top/bottom
+	PreviewSize     string `json:"preview_size"`     // プレビューの大きさ: "50%" または桁数（上下なら行数）
+	Reverse         bool   `json:"reverse"`          // プロンプトを下にしてリストを下から上へ並べる
+	Border          string `json:"border"`           // 区切り線: sharp/thick/double/ascii/none
+}
+
+// プレビューの位置と区切り線として指定できる値
+var (
+	validPreviewPositions = []string{"right", "left", "top", "bottom"}
+	validBorders          = []string{"sharp", "thick", "double", "ascii", "none"}
+)
+
+// Validate はレイアウト設定が有効かチェック
+func (lc LayoutConfig) Validate() error {
+	if !slices.Contains(validPreviewPositions, lc.PreviewPosition) {
+		return fmt.Errorf("layout.preview_position: unknown value %q (valid: %s)",
+			lc.PreviewPosition, strings.Join(validPreviewPositions, ", "))
+	}
+	if _, err := ParsePaneSize(lc.PreviewSize); err != nil {
+		return fmt.Errorf("layout.preview_size: %w", err)
+	}
+	if !slices.Contains(validBorders, lc.Border) {
+		return fmt.Errorf("layout.border: unknown value %q (valid: %s)",
+			lc.Border, strings.Join(validBorders, ", "))
+	}
+	return nil
+}
+
+// 同点時の並び順として指定できる値
+var validTieBreakers = []string{"dirs-first", "files-first", "name", "path-length", "depth"}
+
+// Validate は重みが許容範囲内かチェック
+func (rc RankingConfig) Validate() error {
+	weights := []struct {
+		name  string
+		value int
+		max   int
+	}{
+		{"exact_dir", rc.ExactDir, 100000},
+		{"prefix", rc.Prefix, 100000},
+		{"prefix_dir_bonus", rc.PrefixDirBonus, 100000},
+		{"substring", rc.Substring, 100000},
+		{"substring_position", rc.SubstringPosition, 1000},
+		{"substring_dir_bonus", rc.SubstringDirBonus, 100000},
+		{"fuzzy", rc.Fuzzy, 100000},
+		{"parent_dir", rc.ParentDir, 100000},
+		{"parent_depth", rc.ParentDepth, 1000},
+		{"boundary_bonus", rc.BoundaryBonus, 10000},
+		{"camel_case_bonus", rc.CamelCaseBonus, 10000},
+		{"consecutive_bonus", rc.ConsecutiveBonus, 10000},
+	}
+	for _, w := range weights {
+		if w.value < 0 || w.value > w.max {
+			return fmt.Errorf("ranking.%s must be between 0 and %d (got %d)", w.name, w.max, w.value)
+		}
+	}
+
+	for _, tb := range rc.TieBreakers {
+		known := false
+		for _, v := range validTieBreakers {
+			if tb == v {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("ranking.tie_breakers: unknown value %q (valid: %s)",
+				tb, strings.Join(validTieBreakers, ", "))
+		}
+	}
+
+	return nil
 }
 
 // DefaultConfig はデフォルト設定
@@ -38,25 +146,91 @@ func DefaultConfig() Config {
 		MaxFiles:      100000, // 10万ファイルまで
 		EnablePreview: true,
 		PreviewLines:  20,
+		ScrollOff:     3,
+		SyncOutput:    "auto",
+		Ranking:       DefaultRankingConfig(),
+		Layout:        DefaultLayoutConfig(),
+		Theme:         "dark",
+		LSColors:      true,
+		Icons:         IconConfig{Set: "emoji"},
+		Mouse:         true,
+	}
+}
+
+// DefaultLayoutConfig はデフォルトのレイアウト（右に半分のプレビュー）
+func DefaultLayoutConfig() LayoutConfig {
+	return LayoutConfig{
+		PreviewPosition: "right",
+		PreviewSize:     "50%",
+		Reverse:         false,
+		Border:          "sharp",
+	}
+}
+
+// DefaultRankingConfig はデフォルトのスコアリング設定
+func DefaultRankingConfig() RankingConfig {
+	return RankingConfig{
+		ExactDir:          10000,
+		Prefix:            1000,
+		PrefixDirBonus:    500,
+		Substring:         500,
+		SubstringPosition: 10,
+		SubstringDirBonus: 200,
+		Fuzzy:             200,
+		ParentDir:         100,
+		ParentDepth:       20,
+		BoundaryBonus:     150,
+		CamelCaseBonus:    150,
+		ConsecutiveBonus:  20,
+		TieBreakers:       []string{"dirs-first", "name"},
 	}
 }
 
 // LoadConfig は設定ファイルを読み込む
-func LoadConfig() Config {
+// 読めない・不正な値があっても起動はできるようにデフォルト値で補い、その内容をエラーで返す
+func LoadConfig() (Config, error) {
 	configPath := getConfigPath()
 
 	// 設定ファイルが存在しない場合はデフォルト値を使用
 	data, err := os.ReadFile(configPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return DefaultConfig(), nil
+	}
 	if err != nil {
-		return DefaultConfig()
+		return DefaultConfig(), fmt.Errorf("%w (using defaults)", err)
 	}
 
-	var config Config
+	// 未指定の項目はデフォルト値のまま
+	config := DefaultConfig()
 	if err := json.Unmarshal(data, &config); err != nil {
-		return DefaultConfig()
+		return DefaultConfig(), fmt.Errorf("%s: %w (using defaults)", configPath, err)
+	}
+
+	// 不正な値はそのセクションだけデフォルトに戻す
+	var errs []error
+	if err := config.Ranking.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w (using default ranking)", err))
+		config.Ranking = DefaultRankingConfig()
+	}
+	if err := config.Layout.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w (using default layout)", err))
+		config.Layout = DefaultLayoutConfig()
+	}
+	if _, ok := builtinThemes[config.Theme]; !ok {
+		errs = append(errs, fmt.Errorf("theme: unknown value %q (valid: %s) (using dark)",
+			config.Theme, strings.Join(slices.Sorted(maps.Keys(builtinThemes)), ", ")))
+		config.Theme = "dark"
+	}
+	if err := config.Colors.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w (ignoring color overrides)", err))
+		config.Colors = Theme{}
+	}
+	if err := config.Icons.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("%w (using emoji)", err))
+		config.Icons.Set = "emoji"
 	}
 
-	return config
+	return config, errors.Join(errs...)
 }
 
 // SaveConfig は設定をファイルに保存
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestConfig は一時的な HOME に設定ファイルを置く
+func writeTestConfig(t *testing.T, content string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".config", "fuzzy-filer", "config.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.MaxDepth != DefaultConfig().MaxDepth {
+		t.Errorf("MaxDepth = %d, want default", config.MaxDepth)
+	}
+}
+
+func TestLoadConfigReportsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"壊れた JSON", `{"max_depth": `, "using defaults"},
+		{"範囲外の重み", `{"ranking": {"prefix": -5}}`, "ranking.prefix"},
+		{"不明な tie_breaker", `{"ranking": {"tie_breakers": ["size"]}}`, "size"},
+		{"不明なプレビュー位置", `{"layout": {"preview_position": "middle"}}`, "layout.preview_position"},
+		{"不明なテーマ", `{"theme": "solarized"}`, "theme"},
+		{"不正な色指定", `{"colors": {"cursor": "#12345"}}`, "colors.cursor"},
+		{"不明なアイコンセット", `{"icons": {"set": "unicode"}}`, "icons.set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeTestConfig(t, tt.content)
+			config, err := LoadConfig()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+			// 不正な値は使われずデフォルトで起動できる
+			if config.Ranking.Prefix != DefaultRankingConfig().Prefix {
+				t.Errorf("Ranking.Prefix = %d, want default", config.Ranking.Prefix)
+			}
+		})
+	}
+}
+
+func TestLoadConfigKeepsValidSections(t *testing.T) {
+	// 不正なセクションがあっても他のセクションの値は使う
+	writeTestConfig(t, `{"max_depth": 3, "ranking": {"prefix": -5}}`)
+	config, err := LoadConfig()
+	if err == nil {
+		t.Fatal("expected an error for ranking.prefix")
+	}
+	if config.MaxDepth != 3 {
+		t.Errorf("MaxDepth = %d, want 3", config.MaxDepth)
+	}
+}
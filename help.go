@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	helpPrompt    = "help> "
+	helpKeysWidth = 24 // キー名の欄の幅
+	helpNameWidth = 18 // アクション名の欄の幅
+)
+
+// openHelp はヘルプを開く（検索語とスクロール位置は毎回リセット）
+func (m *Model) openHelp() {
+	m.help = true
+	m.helpQuery = ""
+	m.helpOffset = 0
+}
+
+// handleHelpInput はヘルプ表示中の入力処理
+// 文字入力は検索語になり、移動系のキーでスクロール、終了/ヘルプ/Enter で閉じる
+func (m *Model) handleHelpInput(k Key) {
+	lines := len(m.helpLines())
+	height := m.bodyHeight()
+
+	switch {
+	case m.keymap.Quit.Matches(k), m.keymap.Enter.Matches(k),
+		m.keymap.Help.Matches(k) && (m.helpQuery == "" || !k.IsText()):
+		m.help = false
+	case m.keymap.Down.Matches(k):
+		m.helpOffset++
+	case m.keymap.Up.Matches(k):
+		m.helpOffset--
+	case m.keymap.PageDown.Matches(k):
+		m.helpOffset += height
+	case m.keymap.PageUp.Matches(k):
+		m.helpOffset -= height
+	case m.keymap.First.Matches(k):
+		m.helpOffset = 0
+	case m.keymap.Last.Matches(k):
+		m.helpOffset = lines
+	case m.keymap.Backspace.Matches(k):
+		runes := []rune(m.helpQuery)
+		if len(runes) > 0 {
+			m.helpQuery = string(runes[:len(runes)-1])
+			m.helpOffset = 0
+		}
+	case k.IsText():
+		m.helpQuery += string(k.Rune)
+		m.helpOffset = 0
+	}
+
+	// 見出し1行を除いた行数でスクロール範囲を決める
+	m.helpOffset = max(0, min(m.helpOffset, len(m.helpLines())-height+1))
+}
+
+// helpLines は検索語に一致するアクションの一覧（キー名、アクション名、説明）
+// 設定ファイルで上書きしたキーもそのまま表示される
+func (m *Model) helpLines() []string {
+	query := strings.ToLower(m.helpQuery)
+
+	var lines []string
+	for _, a := range m.keymap.actions() {
+		keys := strings.Join(*a.binding, ", ")
+		if keys == "" {
+			keys = "(なし)"
+		}
+		text := strings.ToLower(keys + " " + a.name + " " + a.description)
+		if query != "" && !strings.Contains(text, query) {
+			continue
+		}
+		lines = append(lines, padRight(truncateWidth(keys, helpKeysWidth-1), helpKeysWidth)+
+			padRight(a.name, helpNameWidth)+a.description)
+	}
+	return lines
+}
+
+// renderHelp はヘルプを本体の代わりに height 行で描画
+func (m *Model) renderHelp(width, height int) []string {
+	title := padRight("キー", helpKeysWidth) + padRight("アクション", helpNameWidth) + "説明"
+	lines := []string{paint(m.colors.Header, truncateWidth(title, width))}
+
+	items := m.helpLines()
+	if len(items) == 0 {
+		lines = append(lines, paint(m.colors.Info, fmt.Sprintf("%q に一致するアクションはありません", m.helpQuery)))
+	}
+	for i := m.helpOffset; i < len(items) && len(lines) < height; i++ {
+		lines = append(lines, truncateWidth(items[i], width))
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// helpPromptLine はヘルプの検索行
+func (m *Model) helpPromptLine() string {
+	return paint(m.colors.Prompt, helpPrompt) + m.helpQuery + "\033[7m \033[0m"
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// アイコンセットとして指定できる値
+var validIconSets = []string{"emoji", "nerd", "ascii", "none"}
+
+// iconExtensions は拡張子ごとの種類（アイコンセットはこの種類で引く）
+var iconExtensions = map[string]string{
+	".go": "go", ".rs": "rust", ".py": "python", ".rb": "ruby",
+	".js": "javascript", ".mjs": "javascript", ".cjs": "javascript", ".jsx": "javascript",
+	".ts": "typescript", ".tsx": "typescript",
+	".c": "c", ".h": "c", ".cpp": "c", ".cc": "c", ".hpp": "c",
+	".java": "java", ".kt": "java",
+	".sh": "shell", ".bash": "shell", ".zsh": "shell", ".fish": "shell",
+	".html": "html", ".htm": "html", ".css": "css", ".scss": "css",
+	".json": "config", ".yaml": "config", ".yml": "config", ".toml": "config", ".ini": "config", ".conf": "config",
+	".md": "markdown", ".markdown": "markdown",
+	".txt": "text", ".log": "text",
+	".png": "image", ".jpg": "image", ".jpeg": "image", ".gif": "image", ".svg": "image", ".webp": "image", ".ico": "image",
+	".zip": "archive", ".tar": "archive", ".gz": "archive", ".tgz": "archive", ".xz": "archive", ".bz2": "archive", ".7z": "archive", ".zst": "archive",
+	".pdf": "pdf",
+	".mp3": "audio", ".wav": "audio", ".flac": "audio", ".ogg": "audio",
+	".mp4": "video", ".mkv": "video", ".mov": "video", ".webm": "video",
+	".sql": "database", ".db": "database", ".sqlite": "database",
+	".lock": "lock",
+}
+
+// iconFilenames はよく知られたファイル名ごとの種類
+var iconFilenames = map[string]string{
+	"makefile":     "make",
+	"gnumakefile":  "make",
+	"dockerfile":   "docker",
+	"go.mod":       "go",
+	"go.sum":       "go",
+	"cargo.toml":   "rust",
+	"cargo.lock":   "rust",
+	"package.json": "javascript",
+	"license":      "license",
+	"copying":      "license",
+	"readme":       "readme",
+	"readme.md":    "readme",
+	".gitignore":   "git",
+	".gitmodules":  "git",
+}
+
+// iconSets は種類ごとのアイコン（種類がなければ file/dir などの基本アイコン）
+var iconSets = map[string]map[string]string{
+	"emoji": {
+		"dir": "📁", "file": "📄", "symlink": "🔗", "executable": "⚙️", "special": "🔌",
+		"go": "🐹", "rust": "🦀", "python": "🐍", "ruby": "💎", "javascript": "📜", "typescript": "📘",
+		"c": "🔷", "java": "☕", "shell": "🐚", "html": "🌐", "css": "🎨", "config": "🔧",
+		"markdown": "📝", "text": "📃", "image": "🖼️", "archive": "📦", "pdf": "📕",
+		"audio": "🎵", "video": "🎬", "database": "🗃️", "lock": "🔒",
+		"make": "🔨", "docker": "🐳", "license": "📜", "readme": "📖", "git": "🌱",
+	},
+	// Nerd Font の私用領域のグリフ（フォントが対応していないと表示できない）
+	"nerd": {
+		"dir": "\uf07b", "file": "\uf15b", "symlink": "\uf0c1", "executable": "\uf489", "special": "\uf2db",
+		"go": "\ue626", "rust": "\ue7a8", "python": "\ue606", "ruby": "\ue739", "javascript": "\ue74e", "typescript": "\ue628",
+		"c": "\ue61e", "java": "\ue738", "shell": "\uf489", "html": "\ue736", "css": "\ue749", "config": "\ue615",
+		"markdown": "\ue609", "text": "\uf15c", "image": "\uf1c5", "archive": "\uf410", "pdf": "\uf1c1",
+		"audio": "\uf001", "video": "\uf03d", "database": "\uf1c0", "lock": "\uf023",
+		"make": "\ue779", "docker": "\uf308", "license": "\uf0e3", "readme": "\uf48a", "git": "\uf1d3",
+	},
+	// ls -F と同じ記号（通常のファイルは空白）
+	"ascii": {
+		"dir": "/", "file": " ", "symlink": "@", "executable": "*", "special": "|",
+	},
+	"none": {},
+}
+
+// IconConfig はアイコンの設定
+type IconConfig struct {
+	Set        string            `json:"set"`        // アイコンセット: emoji/nerd/ascii/none
+	Extensions map[string]string `json:"extensions"` // 拡張子ごとの上書き（".go": "G"）
+	Filenames  map[string]string `json:"filenames"`  // ファイル名ごとの上書き（"Makefile": "M"）
+	Types      map[string]string `json:"types"`      // 種類ごとの上書き（dir/file/symlink/executable/special）
+}
+
+// Validate はアイコンセット名が有効かチェック
+func (ic IconConfig) Validate() error {
+	if !slices.Contains(validIconSets, ic.Set) {
+		return fmt.Errorf("icons.set: unknown value %q (valid: %s)", ic.Set, strings.Join(validIconSets, ", "))
+	}
+	return nil
+}
+
+// Icons はエントリのアイコンを決める
+type Icons struct {
+	set        map[string]string
+	extensions map[string]string // 小文字の拡張子（"." 付き）
+	filenames  map[string]string // 小文字のファイル名
+	types      map[string]string
+	width      int // アイコンの表示幅（揃えるために一番広いものに合わせる）
+}
+
+// NewIcons は設定からアイコンの対応表を作る
+func NewIcons(config IconConfig) Icons {
+	set, ok := iconSets[config.Set]
+	if !ok {
+		set = iconSets["emoji"]
+	}
+
+	icons := Icons{
+		set:        set,
+		extensions: map[string]string{},
+		filenames:  map[string]string{},
+		types:      config.Types,
+	}
+	for ext, icon := range config.Extensions {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		icons.extensions[strings.ToLower(ext)] = icon
+	}
+	for name, icon := range config.Filenames {
+		icons.filenames[strings.ToLower(name)] = icon
+	}
+
+	for _, table := range []map[string]string{set, icons.extensions, icons.filenames, icons.types} {
+		for _, icon := range table {
+			icons.width = max(icons.width, stringWidth(icon))
+		}
+	}
+	return icons
+}
+
+// Icon はエントリのアイコン（名前との間の空白を含む。アイコンなしなら ""）
+func (ic Icons) Icon(entry FileEntry) string {
+	if ic.width == 0 {
+		return ""
+	}
+	return padRight(ic.lookup(entry), ic.width) + " "
+}
+
+// lookup は上書き設定、ファイル名、拡張子、種類の順にアイコンを探す
+func (ic Icons) lookup(entry FileEntry) string {
+	kind := entryKind(entry)
+	if kind != "file" && kind != "executable" {
+		return ic.typeIcon(kind)
+	}
+
+	name := strings.ToLower(filepath.Base(entry.Path))
+	ext := filepath.Ext(name)
+	if icon, ok := ic.filenames[name]; ok {
+		return icon
+	}
+	if icon, ok := ic.extensions[ext]; ok {
+		return icon
+	}
+	if icon, ok := ic.set[iconFilenames[name]]; ok {
+		return icon
+	}
+	if icon, ok := ic.set[iconExtensions[ext]]; ok {
+		return icon
+	}
+	return ic.typeIcon(kind)
+}
+
+// typeIcon は種類ごとのアイコン
+func (ic Icons) typeIcon(kind string) string {
+	if icon, ok := ic.types[kind]; ok {
+		return icon
+	}
+	if icon, ok := ic.set[kind]; ok {
+		return icon
+	}
+	return ic.set["file"]
+}
+
+// entryKind はエントリの種類（dir/symlink/special/executable/file）
+func entryKind(entry FileEntry) string {
+	switch {
+	case entry.IsDir:
+		return "dir"
+	case entry.Mode&os.ModeSymlink != 0:
+		return "symlink"
+	case entry.Mode&(os.ModeNamedPipe|os.ModeSocket|os.ModeDevice|os.ModeCharDevice) != 0:
+		return "special"
+	case entry.Mode&0111 != 0:
+		return "executable"
+	}
+	return "file"
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// KeyCode はキーの種類
+type KeyCode int
+
+const (
+	KeyRune      KeyCode = iota // 通常の文字（Ctrl+文字 も含む）
+	KeyEnter                    // Enter
+	KeyTab                      // Tab
+	KeyBackspace                // Backspace / DEL
+	KeyEscape                   // 単独の ESC
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPgUp
+	KeyPgDn
+	KeyInsert
+	KeyDelete
+	KeyPaste   // ブラケットペースト（Paste に内容）
+	KeyMouse   // マウス操作（Mouse に内容）
+	KeyUnknown // 解釈できないシーケンス
+	KeyF1      // F1〜F12 は KeyF1+n
+)
+
+// keyNames はキー名（キーマップで使う表記）
+var keyNames = map[KeyCode]string{
+	KeyEnter:     "enter",
+	KeyTab:       "tab",
+	KeyBackspace: "backspace",
+	KeyEscape:    "esc",
+	KeyUp:        "up",
+	KeyDown:      "down",
+	KeyLeft:      "left",
+	KeyRight:     "right",
+	KeyHome:      "home",
+	KeyEnd:       "end",
+	KeyPgUp:      "pgup",
+	KeyPgDn:      "pgdn",
+	KeyInsert:    "insert",
+	KeyDelete:    "delete",
+	KeyPaste:     "paste",
+	KeyMouse:     "mouse",
+	KeyUnknown:   "unknown",
+}
+
+// Key はデコード済みのキーイベント
+type Key struct {
+	Code  KeyCode
+	Rune  rune // KeyRune のときの文字
+	Ctrl  bool
+	Alt   bool
+	Shift bool
+	Paste string     // KeyPaste のときの貼り付け内容
+	Mouse MouseEvent // KeyMouse のときの操作内容
+}
+
+// マウスのボタン番号
+const (
+	MouseLeft      = 0
+	MouseMiddle    = 1
+	MouseRight     = 2
+	MouseWheelUp   = 64
+	MouseWheelDown = 65
+)
+
+// MouseEvent はマウス操作（SGR 1006 形式で報告されたもの）
+type MouseEvent struct {
+	Button  int  // MouseLeft, MouseWheelUp など
+	X, Y    int  // 画面上の桁と行（1始まり）
+	Release bool // ボタンを離した
+	Motion  bool // ボタンを押したままの移動
+}
+
+// String は "ctrl-n" "alt-b" "shift-tab" "f1" のようなキー名
+func (k Key) String() string {
+	var name string
+	switch {
+	case k.Code == KeyRune && k.Rune == ' ':
+		name = "space"
+	case k.Code == KeyRune:
+		name = string(k.Rune)
+	case k.Code >= KeyF1:
+		name = "f" + strconv.Itoa(int(k.Code-KeyF1)+1)
+	default:
+		name = keyNames[k.Code]
+	}
+
+	if k.Shift {
+		name = "shift-" + name
+	}
+	if k.Alt {
+		name = "alt-" + name
+	}
+	if k.Ctrl {
+		name = "ctrl-" + name
+	}
+	return name
+}
+
+// IsText はクエリに入力する通常文字か（マルチバイト文字を含む）
+func (k Key) IsText() bool {
+	return k.Code == KeyRune && !k.Ctrl && !k.Alt && unicode.IsPrint(k.Rune)
+}
+
+const (
+	escTimeout      = 100 * time.Millisecond // 単独 ESC と判断するまでの待ち時間（ESCDELAY で変更可）
+	seqTimeout      = 500 * time.Millisecond // 始まったシーケンスの続きを待つ時間（SSH 越しで分割されても読めるように）
+	pasteEnd        = "\033[201~"            // ブラケットペースト終了
+	modifierShift   = 1
+	modifierAlt     = 2
+	modifierControl = 4
+)
+
+// KeyReader は端末からの入力をキーイベントに変換する
+type KeyReader struct {
+	runes    chan rune
+	keys     chan Key
+	err      error
+	escDelay time.Duration
+}
+
+// NewKeyReader は r を読み続けるゴルーチンを起動する
+func NewKeyReader(r io.Reader) *KeyReader {
+	kr := &KeyReader{runes: make(chan rune, 256), escDelay: escDelay()}
+	go func() {
+		reader := bufio.NewReader(r)
+		for {
+			ch, _, err := reader.ReadRune()
+			if err != nil {
+				kr.err = err
+				close(kr.runes)
+				return
+			}
+			kr.runes <- ch
+		}
+	}()
+	return kr
+}
+
+// Buffered はまだ処理していない入力があるか
+func (kr *KeyReader) Buffered() bool {
+	return len(kr.runes) > 0 || len(kr.keys) > 0
+}
+
+// Keys はキーイベントを順に流すチャネルを返す（入力が終わると閉じる）
+// シグナルなど他のイベントと select で待つときに使う
+func (kr *KeyReader) Keys() <-chan Key {
+	if kr.keys == nil {
+		kr.keys = make(chan Key, 64)
+		go func() {
+			defer close(kr.keys)
+			for {
+				key, err := kr.ReadKey()
+				if err != nil {
+					return
+				}
+				kr.keys <- key
+			}
+		}()
+	}
+	return kr.keys
+}
+
+// ReadKey は次のキーイベントを返す（入力があるまでブロック）
+func (kr *KeyReader) ReadKey() (Key, error) {
+	r, ok := <-kr.runes
+	if !ok {
+		return Key{}, kr.readErr()
+	}
+	if r != '\033' {
+		return decodeRune(r), nil
+	}
+	return kr.readEscape(), nil
+}
+
+// readEscape は ESC に続くシーケンスを読む
+func (kr *KeyReader) readEscape() Key {
+	// ESC の直後に続きが来なければ単独の ESC
+	next, ok := kr.readTimeout(kr.escDelay)
+	if !ok {
+		return Key{Code: KeyEscape}
+	}
+
+	var key Key
+	switch next {
+	case '[':
+		return kr.readCSI()
+	case 'O':
+		return kr.readSS3()
+	case '\033':
+		// Alt+矢印を ESC ESC [ A と送る端末があるので、続きのシーケンスに Alt を付ける
+		key = kr.readEscape()
+	default:
+		// ESC + 文字 は Alt+文字
+		key = decodeRune(next)
+	}
+	key.Alt = true
+	return key
+}
+
+// escDelay は単独 ESC と判断するまでの待ち時間（ncurses と同じく ESCDELAY にミリ秒で指定できる）
+func escDelay() time.Duration {
+	if ms, err := strconv.Atoi(os.Getenv("ESCDELAY")); err == nil && ms >= 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return escTimeout
+}
+
+func (kr *KeyReader) readErr() error {
+	if kr.err != nil {
+		return kr.err
+	}
+	return io.EOF
+}
+
+// readTimeout は timeout 以内に来た次の文字を返す
+func (kr *KeyReader) readTimeout(timeout time.Duration) (rune, bool) {
+	select {
+	case r, ok := <-kr.runes:
+		return r, ok
+	case <-time.After(timeout):
+		return 0, false
+	}
+}
+
+// readCSI は ESC [ に続くパラメータと終端文字を読む
+func (kr *KeyReader) readCSI() Key {
+	var params strings.Builder
+	for {
+		r, ok := kr.readTimeout(seqTimeout)
+		if !ok {
+			return Key{Code: KeyUnknown}
+		}
+		// 0x40〜0x7E が終端文字
+		if r >= 0x40 && r <= 0x7e {
+			key := decodeCSI(params.String(), r)
+			if key.Code == KeyPaste {
+				key.Paste = kr.readPaste()
+			}
+			return key
+		}
+		params.WriteRune(r)
+	}
+}
+
+// readSS3 は ESC O に続く1文字を読む（アプリケーションカーソルモード）
+func (kr *KeyReader) readSS3() Key {
+	r, ok := kr.readTimeout(seqTimeout)
+	if !ok {
+		return Key{Code: KeyRune, Rune: 'O', Alt: true}
+	}
+	return decodeFinal(r)
+}
+
+// readPaste はペースト終了シーケンスまでを読む
+func (kr *KeyReader) readPaste() string {
+	var b strings.Builder
+	for {
+		r, ok := <-kr.runes
+		if !ok {
+			return b.String()
+		}
+		b.WriteRune(r)
+		if r == '~' && strings.HasSuffix(b.String(), pasteEnd) {
+			return strings.TrimSuffix(b.String(), pasteEnd)
+		}
+	}
+}
+
+// decodeRune は1文字のキーを解釈する
+func decodeRune(r rune) Key {
+	switch {
+	case r == '\r' || r == '\n':
+		return Key{Code: KeyEnter}
+	case r == '\t':
+		return Key{Code: KeyTab}
+	case r == 0x7f || r == '\b':
+		return Key{Code: KeyBackspace}
+	case r == 0:
+		return Key{Code: KeyRune, Rune: ' ', Ctrl: true}
+	case r < 0x1b:
+		// Ctrl+A〜Ctrl+Z
+		return Key{Code: KeyRune, Rune: 'a' + r - 1, Ctrl: true}
+	case r < 0x20:
+		// Ctrl+\ Ctrl+] Ctrl+^ Ctrl+_
+		return Key{Code: KeyRune, Rune: r + 0x40, Ctrl: true}
+	}
+	return Key{Code: KeyRune, Rune: r}
+}
+
+// decodeCSI は CSI シーケンス（ESC [ params final）を解釈する
+func decodeCSI(params string, final rune) Key {
+	if mouse, ok := strings.CutPrefix(params, "<"); ok && (final == 'M' || final == 'm') {
+		return decodeMouse(mouse, final == 'm')
+	}
+
+	fields := strings.Split(params, ";")
+	modifier := 0
+	if len(fields) > 1 {
+		modifier, _ = strconv.Atoi(fields[1])
+	}
+
+	var key Key
+	if final == '~' {
+		n, _ := strconv.Atoi(fields[0])
+		key = decodeTilde(n)
+	} else {
+		key = decodeFinal(final)
+	}
+	applyModifier(&key, modifier)
+	return key
+}
+
+// decodeMouse は SGR マウス報告（ESC [ < b ; x ; y M/m）を解釈する
+// b の下位2ビットとホイール(64)がボタン、4/8/16 が Shift/Alt/Ctrl、32 が移動
+func decodeMouse(params string, release bool) Key {
+	fields := strings.Split(params, ";")
+	if len(fields) != 3 {
+		return Key{Code: KeyUnknown}
+	}
+	b, err1 := strconv.Atoi(fields[0])
+	x, err2 := strconv.Atoi(fields[1])
+	y, err3 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return Key{Code: KeyUnknown}
+	}
+
+	return Key{
+		Code:  KeyMouse,
+		Shift: b&4 != 0,
+		Alt:   b&8 != 0,
+		Ctrl:  b&16 != 0,
+		Mouse: MouseEvent{
+			Button:  b &^ (4 | 8 | 16 | 32),
+			X:       x,
+			Y:       y,
+			Release: release,
+			Motion:  b&32 != 0,
+		},
+	}
+}
+
+// decodeFinal は CSI/SS3 の終端文字だけで決まるキー
+func decodeFinal(final rune) Key {
+	switch final {
+	case 'A':
+		return Key{Code: KeyUp}
+	case 'B':
+		return Key{Code: KeyDown}
+	case 'C':
+		return Key{Code: KeyRight}
+	case 'D':
+		return Key{Code: KeyLeft}
+	case 'H':
+		return Key{Code: KeyHome}
+	case 'F':
+		return Key{Code: KeyEnd}
+	case 'Z':
+		return Key{Code: KeyTab, Shift: true}
+	case 'P', 'Q', 'R', 'S':
+		return Key{Code: KeyF1 + KeyCode(final-'P')}
+	}
+	return Key{Code: KeyUnknown}
+}
+
+// decodeTilde は "ESC [ n ~" 形式のキー
+func decodeTilde(n int) Key {
+	switch n {
+	case 1, 7:
+		return Key{Code: KeyHome}
+	case 2:
+		return Key{Code: KeyInsert}
+	case 3:
+		return Key{Code: KeyDelete}
+	case 4, 8:
+		return Key{Code: KeyEnd}
+	case 5:
+		return Key{Code: KeyPgUp}
+	case 6:
+		return Key{Code: KeyPgDn}
+	case 200:
+		return Key{Code: KeyPaste}
+	}
+
+	// F1〜F12 は番号が飛び飛び
+	fkeys := []int{11, 12, 13, 14, 15, 17, 18, 19, 20, 21, 23, 24}
+	for i, code := range fkeys {
+		if n == code {
+			return Key{Code: KeyF1 + KeyCode(i)}
+		}
+	}
+	return Key{Code: KeyUnknown}
+}
+
+// applyModifier は xterm の修飾キー番号（1 + ビットフラグ）を反映する
+func applyModifier(key *Key, modifier int) {
+	if modifier < 2 {
+		return
+	}
+	bits := modifier - 1
+	key.Shift = key.Shift || bits&modifierShift != 0
+	key.Alt = bits&modifierAlt != 0
+	key.Ctrl = bits&modifierControl != 0
+}
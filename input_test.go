@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// readKeys は input をすべてキーイベントに変換する
+func readKeys(t *testing.T, input string) []Key {
+	t.Helper()
+	kr := NewKeyReader(strings.NewReader(input))
+	var keys []Key
+	for {
+		key, err := kr.ReadKey()
+		if err != nil {
+			return keys
+		}
+		keys = append(keys, key)
+	}
+}
+
+func TestReadKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"文字", "ab", []string{"a", "b"}},
+		{"マルチバイト", "あ😀", []string{"あ", "😀"}},
+		{"制御文字", "\x01\r\t\x7f\x00", []string{"ctrl-a", "enter", "tab", "backspace", "ctrl-space"}},
+		{"矢印 (CSI)", "\033[A\033[B\033[C\033[D", []string{"up", "down", "right", "left"}},
+		{"矢印 (SS3)", "\033OA\033OH", []string{"up", "home"}},
+		{"チルダ形式", "\033[1~\033[3~\033[4~\033[5~\033[6~", []string{"home", "delete", "end", "pgup", "pgdn"}},
+		{"ファンクションキー", "\033OP\033[15~\033[24~", []string{"f1", "f5", "f12"}},
+		{"修飾キー", "\033[1;5C\033[1;3A\033[1;2B\033[3;5~", []string{"ctrl-right", "alt-up", "shift-down", "ctrl-delete"}},
+		{"Shift+Tab", "\033[Z", []string{"shift-tab"}},
+		{"Alt+文字", "\033b\033\x7f", []string{"alt-b", "alt-backspace"}},
+		{"ESC ESC で送る Alt+矢印", "\033\033[A\033\033OB", []string{"alt-up", "alt-down"}},
+		{"ESC ESC のあとの CSI を文字にしない", "\033\033[1;5Cx", []string{"ctrl-alt-right", "x"}},
+		{"単独の ESC", "\033", []string{"esc"}},
+		{"ESC ESC", "\033\033", []string{"alt-esc"}},
+		{"不明なシーケンス", "\033[99Xq", []string{"unknown", "q"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, key := range readKeys(t, tt.input) {
+				got = append(got, key.String())
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("%q = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadKeyPaste(t *testing.T) {
+	keys := readKeys(t, "\033[200~foo\033[Abar\n\033[201~z")
+	if len(keys) != 2 || keys[0].Code != KeyPaste || keys[1].String() != "z" {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if want := "foo\033[Abar\n"; keys[0].Paste != want {
+		t.Errorf("Paste = %q, want %q", keys[0].Paste, want)
+	}
+}
+
+func TestReadKeyMouse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  MouseEvent
+		ctrl  bool
+	}{
+		{"\033[<0;10;5M", MouseEvent{Button: MouseLeft, X: 10, Y: 5}, false},
+		{"\033[<0;10;5m", MouseEvent{Button: MouseLeft, X: 10, Y: 5, Release: true}, false},
+		{"\033[<65;1;2M", MouseEvent{Button: MouseWheelDown, X: 1, Y: 2}, false},
+		{"\033[<32;3;4M", MouseEvent{Button: MouseLeft, X: 3, Y: 4, Motion: true}, false},
+		{"\033[<16;7;8M", MouseEvent{Button: MouseLeft, X: 7, Y: 8}, true},
+	}
+
+	for _, tt := range tests {
+		keys := readKeys(t, tt.input)
+		if len(keys) != 1 || keys[0].Code != KeyMouse {
+			t.Errorf("%q = %+v, want one mouse event", tt.input, keys)
+			continue
+		}
+		if keys[0].Mouse != tt.want || keys[0].Ctrl != tt.ctrl {
+			t.Errorf("%q = %+v (ctrl %v), want %+v (ctrl %v)", tt.input, keys[0].Mouse, keys[0].Ctrl, tt.want, tt.ctrl)
+		}
+	}
+}
+
+func TestEscDelay(t *testing.T) {
+	t.Setenv("ESCDELAY", "")
+	if got := escDelay(); got != escTimeout {
+		t.Errorf("default escDelay = %v, want %v", got, escTimeout)
+	}
+	t.Setenv("ESCDELAY", "250")
+	if got := escDelay(); got != 250*time.Millisecond {
+		t.Errorf("ESCDELAY=250 escDelay = %v, want 250ms", got)
+	}
+}
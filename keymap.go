@@ -1,26 +1,283 @@
 package main
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Binding はアクションに割り当てたキー名の一覧（"ctrl-n", "down" など）
+type Binding []string
+
+// Matches はキーがこのバインドに含まれるか
+func (b Binding) Matches(k Key) bool {
+	name := k.String()
+	for _, s := range b {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
 // KeyMap はキーバインド設定
 type KeyMap struct {
-	Quit        rune
-	Up          rune
-	Down        rune
-	Enter       rune
-	Backspace   rune
-	DeleteQuery rune
+	Quit           Binding
+	Up             Binding
+	Down           Binding
+	PageUp         Binding
+	PageDown       Binding
+	HalfPageUp     Binding
+	HalfPageDown   Binding
+	First          Binding
+	Last           Binding
+	Enter          Binding
+	AcceptMarked   Binding
+	ToggleMarkDown Binding
+	ToggleMarkUp   Binding
+	MarkAll        Binding
+	InvertMarks    Binding
+	Backspace      Binding
+	DeleteChar     Binding
+	CharLeft       Binding
+	CharRight      Binding
+	WordLeft       Binding
+	WordRight      Binding
+	LineStart      Binding
+	LineEnd        Binding
+	DeleteWord     Binding
+	KillLineStart  Binding
+	KillLineEnd    Binding
+	Yank           Binding
+	ToggleRegex    Binding
+	ToggleExplain  Binding
+	TogglePreview  Binding
+	PreviewLarger  Binding
+	PreviewSmaller Binding
+	CycleSort      Binding
+	ReverseSort    Binding
+	ToggleTree     Binding
+	Expand         Binding
+	Collapse       Binding
+	ToggleColumns  Binding
+	ParentDir      Binding
+	ChildDir       Binding
+	Help           Binding
 }
 
 // DefaultKeyMap はデフォルトキーマップ
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
-		Quit:        0x04, // 終了
-		Up:          0x10, // 上移動
-		Down:        0x0e, // 下移動
-		Enter:       '\r', // 選択/ディレクトリ移動
-		Backspace:   '\b', // クエリ削除
-		DeleteQuery: 0x7f, // DELキー
+		Quit:           Binding{"ctrl-d", "ctrl-c", "esc"}, // 終了
+		Up:             Binding{"ctrl-p", "up"},            // 上移動
+		Down:           Binding{"ctrl-n", "down"},          // 下移動
+		PageUp:         Binding{"ctrl-b", "pgup"},          // 1ページ上
+		PageDown:       Binding{"ctrl-f", "pgdn"},          // 1ページ下
+		HalfPageUp:     Binding{"alt-v"},                   // 半ページ上
+		HalfPageDown:   Binding{"ctrl-v"},                  // 半ページ下
+		First:          Binding{"ctrl-g", "home", "alt-<"}, // 先頭へ
+		Last:           Binding{"ctrl-l", "end", "alt->"},  // 末尾へ
+		Enter:          Binding{"enter"},                   // 選択/ディレクトリ移動
+		AcceptMarked:   Binding{"alt-enter"},               // マークしたパスで確定
+		ToggleMarkDown: Binding{"tab"},                     // マーク切替して下へ
+		ToggleMarkUp:   Binding{"shift-tab"},               // マーク切替して上へ
+		MarkAll:        Binding{"alt-a"},                   // 表示中をすべてマーク
+		InvertMarks:    Binding{"alt-i"},                   // 表示中のマークを反転
+		Backspace:      Binding{"backspace"},               // カーソル前の1文字削除（BS/DEL）
+		DeleteChar:     Binding{"delete"},                  // カーソル位置の1文字削除
+		CharLeft:       Binding{"left"},                    // クエリ内で左へ
+		CharRight:      Binding{"right"},                   // クエリ内で右へ
+		WordLeft:       Binding{"alt-b", "ctrl-left"},      // 前の単語へ
+		WordRight:      Binding{"alt-f", "ctrl-right"},     // 次の単語へ
+		LineStart:      Binding{"ctrl-a"},                  // クエリの先頭へ
+		LineEnd:        Binding{"ctrl-e"},                  // クエリの末尾へ
+		DeleteWord:     Binding{"ctrl-w"},                  // 前の単語を削除
+		KillLineStart:  Binding{"ctrl-u"},                  // 先頭まで削除
+		KillLineEnd:    Binding{"ctrl-k"},                  // 末尾まで削除
+		Yank:           Binding{"ctrl-y"},                  // 削除した文字列を貼り付け
+		ToggleRegex:    Binding{"ctrl-r"},                  // 正規表現モード切替
+		ToggleExplain:  Binding{"ctrl-x"},                  // スコア内訳表示切替
+		TogglePreview:  Binding{"alt-p"},                   // プレビュー表示切替
+		PreviewLarger:  Binding{"alt-+", "alt-="},          // プレビューを大きく
+		PreviewSmaller: Binding{"alt--"},                   // プレビューを小さく
+		CycleSort:      Binding{"ctrl-s"},                  // 並び順切替
+		ReverseSort:    Binding{"ctrl-t"},                  // 昇順/降順切替
+		ToggleTree:     Binding{"alt-t"},                   // ツリー表示切替
+		Expand:         Binding{"right"},                   // ツリーでディレクトリを展開（クエリが空のとき）
+		Collapse:       Binding{"left"},                    // ツリーでディレクトリを折りたたむ（クエリが空のとき）
+		ToggleColumns:  Binding{"alt-m"},                   // 3列表示切替
+		ParentDir:      Binding{"left", "alt-h"},           // 3列表示で親ディレクトリへ（← はクエリが空のとき）
+		ChildDir:       Binding{"right", "alt-l"},          // 3列表示で子ディレクトリへ（→ はクエリが空のとき）
+		Help:           Binding{"?", "f1"},                 // ヘルプ表示（"?" はクエリが空のときだけ）
 	}
 }
 
-// TODO: 将来的に設定ファイル（~/.config/fuzzy-filer/config.yaml）から読み込む
-// これでユーザーが自由にカスタマイズできる
+// keyAction はキーマップの1項目
+type keyAction struct {
+	name        string   // 設定ファイルで使う名前
+	description string   // ヘルプに出す説明
+	binding     *Binding // KeyMap の対応するフィールド
+}
+
+// actions はすべてのアクション（ヘルプの表示順）
+func (km *KeyMap) actions() []keyAction {
+	return []keyAction{
+		{"up", "上へ移動", &km.Up},
+		{"down", "下へ移動", &km.Down},
+		{"page-up", "1ページ上へ", &km.PageUp},
+		{"page-down", "1ページ下へ", &km.PageDown},
+		{"half-page-up", "半ページ上へ", &km.HalfPageUp},
+		{"half-page-down", "半ページ下へ", &km.HalfPageDown},
+		{"first", "先頭へ", &km.First},
+		{"last", "末尾へ", &km.Last},
+		{"enter", "選択（ディレクトリなら移動）", &km.Enter},
+		{"accept-marked", "マークしたパスで確定", &km.AcceptMarked},
+		{"toggle-mark-down", "マークを切り替えて下へ", &km.ToggleMarkDown},
+		{"toggle-mark-up", "マークを切り替えて上へ", &km.ToggleMarkUp},
+		{"mark-all", "表示中をすべてマーク", &km.MarkAll},
+		{"invert-marks", "表示中のマークを反転", &km.InvertMarks},
+		{"backspace", "カーソル前の1文字を削除", &km.Backspace},
+		{"delete-char", "カーソル位置の1文字を削除", &km.DeleteChar},
+		{"char-left", "クエリ内で左へ", &km.CharLeft},
+		{"char-right", "クエリ内で右へ", &km.CharRight},
+		{"word-left", "前の単語へ", &km.WordLeft},
+		{"word-right", "次の単語へ", &km.WordRight},
+		{"line-start", "クエリの先頭へ", &km.LineStart},
+		{"line-end", "クエリの末尾へ", &km.LineEnd},
+		{"delete-word", "前の単語を削除", &km.DeleteWord},
+		{"kill-line-start", "先頭まで削除", &km.KillLineStart},
+		{"kill-line-end", "末尾まで削除", &km.KillLineEnd},
+		{"yank", "削除した文字列を貼り付け", &km.Yank},
+		{"toggle-regex", "正規表現モード切替", &km.ToggleRegex},
+		{"toggle-explain", "スコア内訳表示切替", &km.ToggleExplain},
+		{"toggle-preview", "プレビュー表示切替", &km.TogglePreview},
+		{"preview-larger", "プレビューを大きく", &km.PreviewLarger},
+		{"preview-smaller", "プレビューを小さく", &km.PreviewSmaller},
+		{"cycle-sort", "並び順切替", &km.CycleSort},
+		{"reverse-sort", "昇順/降順切替", &km.ReverseSort},
+		{"toggle-tree", "ツリー表示切替", &km.ToggleTree},
+		{"expand", "ツリーでディレクトリを展開", &km.Expand},
+		{"collapse", "ツリーでディレクトリを折りたたむ", &km.Collapse},
+		{"toggle-columns", "3列表示切替（親/現在/プレビュー）", &km.ToggleColumns},
+		{"parent-dir", "3列表示で親ディレクトリへ（← はクエリが空のときだけ）", &km.ParentDir},
+		{"child-dir", "3列表示で子ディレクトリへ（→ はクエリが空のときだけ）", &km.ChildDir},
+		{"help", "このヘルプ", &km.Help},
+		{"quit", "終了", &km.Quit},
+	}
+}
+
+// Apply は設定ファイルのキーバインド（アクション名 → キー名の一覧）で上書きする
+// 空の一覧を指定するとそのアクションを無効にできる
+// 不明なアクション名はそれだけを無視し、他の上書きは反映したうえでエラーを返す
+func (km *KeyMap) Apply(overrides map[string][]string) error {
+	actions := km.actions()
+	var unknown, unreadable []string
+	for name, keys := range overrides {
+		found := false
+		for _, a := range actions {
+			if a.name == name {
+				// 入力から作られないキー名は割り当てても押せないので除く
+				binding := Binding{}
+				for _, key := range keys {
+					if isReadableKey(key) {
+						binding = append(binding, key)
+					} else {
+						unreadable = append(unreadable, name+": "+strconv.Quote(key))
+					}
+				}
+				*a.binding = binding
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, strconv.Quote(name))
+		}
+	}
+
+	var errs []error
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		errs = append(errs, fmt.Errorf("keys: unknown action %s (ignored)", strings.Join(unknown, ", ")))
+	}
+	if len(unreadable) > 0 {
+		sort.Strings(unreadable)
+		errs = append(errs, fmt.Errorf("keys: key name the terminal never sends %s (ignored)", strings.Join(unreadable, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// isReadableKey は name が KeyReader の返すキー（Key.String）になりうるか
+// ctrl-j や ctrl-m のように別のキー（enter など）として読まれるものは false
+func isReadableKey(name string) bool {
+	var key Key
+	base := name
+	base, key.Ctrl = strings.CutPrefix(base, "ctrl-")
+	base, key.Alt = strings.CutPrefix(base, "alt-")
+	base, key.Shift = strings.CutPrefix(base, "shift-")
+
+	switch code, ok := keyCodeByName(base); {
+	case base == "space":
+		key.Rune = ' '
+	case ok:
+		key.Code = code
+	case utf8.RuneCountInString(base) == 1:
+		key.Rune, _ = utf8.DecodeRuneInString(base)
+		if !unicode.IsPrint(key.Rune) {
+			return false
+		}
+	default:
+		return false
+	}
+	// 修飾キーの順序や "space" の表記も Key.String と揃っている必要がある
+	if key.String() != name {
+		return false
+	}
+
+	switch key.Code {
+	case KeyRune:
+		if key.Shift {
+			// Shift+文字は大文字などの文字そのものとして届く
+			return false
+		}
+		if key.Ctrl {
+			// Ctrl+文字として届くのは decodeRune が変換する制御文字だけ
+			r := key.Rune
+			return (r >= 'a' && r <= 'z' && !strings.ContainsRune("hijm", r)) || strings.ContainsRune(" \\]^_", r)
+		}
+	case KeyEnter, KeyBackspace, KeyEscape:
+		// 修飾は ESC を前に付けた Alt だけ
+		return !key.Ctrl && !key.Shift
+	case KeyTab:
+		return !key.Ctrl
+	}
+	return true
+}
+
+// keyCodeByName はキー名（"enter" や "f5"）からキーコードを引く
+// paste や mouse のように割り当てに使えないものは含まない
+func keyCodeByName(name string) (KeyCode, bool) {
+	if n, ok := strings.CutPrefix(name, "f"); ok {
+		if i, err := strconv.Atoi(n); err == nil && i >= 1 && i <= 12 && strconv.Itoa(i) == n {
+			return KeyF1 + KeyCode(i-1), true
+		}
+	}
+	for code, s := range keyNames {
+		if s == name && code != KeyPaste && code != KeyMouse && code != KeyUnknown {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// hint はフッターに出す代表のキー名（割り当てがなければ "-"）
+func (b Binding) hint() string {
+	if len(b) == 0 {
+		return "-"
+	}
+	return b[0]
+}
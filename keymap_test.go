@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyMapApply(t *testing.T) {
+	km := DefaultKeyMap()
+	err := km.Apply(map[string][]string{
+		"quit":         {"ctrl-q"},
+		"toggle-tree":  {},
+		"no-such-key":  {"x"},
+		"another-typo": {"y"},
+	})
+
+	// 不明なアクション名はすべてエラーに挙げる
+	if err == nil || !strings.Contains(err.Error(), `"no-such-key"`) || !strings.Contains(err.Error(), `"another-typo"`) {
+		t.Errorf("err = %v, want both unknown actions reported", err)
+	}
+	// 正しいアクション名の上書きは反映される
+	if !km.Quit.Matches(Key{Code: KeyRune, Rune: 'q', Ctrl: true}) || km.Quit.Matches(Key{Code: KeyRune, Rune: 'c', Ctrl: true}) {
+		t.Errorf("Quit = %v, want [ctrl-q]", km.Quit)
+	}
+	if len(km.ToggleTree) != 0 {
+		t.Errorf("ToggleTree = %v, want it disabled", km.ToggleTree)
+	}
+	// 上書きしていないアクションはデフォルトのまま
+	if !km.Help.Matches(Key{Code: KeyF1}) {
+		t.Errorf("Help = %v, want the default binding", km.Help)
+	}
+}
+
+func TestKeyMapActionNamesAreUnique(t *testing.T) {
+	km := DefaultKeyMap()
+	seen := make(map[string]bool)
+	for _, a := range km.actions() {
+		if seen[a.name] {
+			t.Errorf("duplicate action name %q", a.name)
+		}
+		seen[a.name] = true
+	}
+}
+
+func TestKeyMapApplyReportsUnreadableKeys(t *testing.T) {
+	km := DefaultKeyMap()
+	err := km.Apply(map[string][]string{"down": {"ctrl-n", "ctrl-j", "down"}})
+
+	// \n は enter として読まれるので ctrl-j は押せない
+	if err == nil || !strings.Contains(err.Error(), `down: "ctrl-j"`) {
+		t.Errorf("err = %v, want ctrl-j reported", err)
+	}
+	if strings.Join(km.Down, " ") != "ctrl-n down" {
+		t.Errorf("Down = %v, want [ctrl-n down]", km.Down)
+	}
+}
+
+func TestIsReadableKey(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"ctrl-n", true},
+		{"ctrl-space", true},
+		{"ctrl-]", true},
+		{"alt-<", true},
+		{"alt--", true},
+		{"alt-enter", true},
+		{"shift-tab", true},
+		{"ctrl-alt-right", true},
+		{"f12", true},
+		{"?", true},
+		{"ctrl-j", false}, // enter
+		{"ctrl-m", false}, // enter
+		{"ctrl-i", false}, // tab
+		{"ctrl-h", false}, // backspace
+		{"ctrl-N", false},
+		{"shift-a", false},
+		{"ctrl-enter", false},
+		{"alt-ctrl-x", false}, // 修飾キーの順序が違う
+		{" ", false},          // "space" と書く
+		{"f13", false},
+		{"paste", false},
+		{"pageup", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isReadableKey(tt.name); got != tt.want {
+			t.Errorf("isReadableKey(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultKeysAreReadable(t *testing.T) {
+	km := DefaultKeyMap()
+	for _, a := range km.actions() {
+		for _, key := range *a.binding {
+			if !isReadableKey(key) {
+				t.Errorf("%s: default key %q is never read", a.name, key)
+			}
+		}
+	}
+}
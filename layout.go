@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	minListWidth     = 20 // 左右プレビュー時にリストに残す最小幅
+	minPreviewHeight = 5  // これより本体が低いと上下プレビューを出さない
+	previewStepCols  = 4  // 桁数指定のときの1回の拡大縮小幅
+	previewStepRatio = 5  // 割合指定のときの1回の拡大縮小幅（%）
+)
+
+// PaneSize はプレビューの大きさ（割合か固定の桁数/行数）
+type PaneSize struct {
+	Value   int
+	Percent bool
+}
+
+// ParsePaneSize は "50%" や "60" を PaneSize にする
+func ParsePaneSize(s string) (PaneSize, error) {
+	if percent, ok := strings.CutSuffix(s, "%"); ok {
+		n, err := strconv.Atoi(percent)
+		if err != nil || n < 1 || n > 99 {
+			return PaneSize{}, fmt.Errorf("invalid size %q (1%%〜99%%)", s)
+		}
+		return PaneSize{Value: n, Percent: true}, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return PaneSize{}, fmt.Errorf("invalid size %q (桁数か割合)", s)
+	}
+	return PaneSize{Value: n}, nil
+}
+
+// String は設定ファイルと同じ表記
+func (p PaneSize) String() string {
+	if p.Percent {
+		return fmt.Sprintf("%d%%", p.Value)
+	}
+	return strconv.Itoa(p.Value)
+}
+
+// resolve は全体の大きさ total に対する桁数/行数
+func (p PaneSize) resolve(total int) int {
+	if p.Percent {
+		return total * p.Value / 100
+	}
+	return p.Value
+}
+
+// resize は delta 段階だけ大きく（負なら小さく）する
+func (p PaneSize) resize(delta int) PaneSize {
+	if p.Percent {
+		p.Value = max(10, min(90, p.Value+delta*previewStepRatio))
+	} else {
+		p.Value = max(1, p.Value+delta*previewStepCols)
+	}
+	return p
+}
+
+// borderStyle は区切り線に使う文字
+type borderStyle struct {
+	horizontal string
+	vertical   string
+	teeDown    string // 横線から下へ分岐（┬）
+	teeUp      string // 横線から上へ分岐（┴）
+}
+
+var borderStyles = map[string]borderStyle{
+	"sharp":  {"─", "│", "┬", "┴"},
+	"thick":  {"━", "┃", "┳", "┻"},
+	"double": {"═", "║", "╦", "╩"},
+	"ascii":  {"-", "|", "+", "+"},
+	"none":   {" ", " ", " ", " "},
+}
+
+// SetLayout はレイアウトを変更する（プレビューの大きさも設定値に戻す）
+func (m *Model) SetLayout(layout LayoutConfig) error {
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+	size, _ := ParsePaneSize(layout.PreviewSize)
+	m.config.Layout = layout
+	m.previewSize = size
+	return nil
+}
+
+// bodyHeight はリストとプレビューに使える行数（ヘッダー3行とフッター2行を除く）
+func (m *Model) bodyHeight() int {
+	return max(1, m.height-5)
+}
+
+// previewPane はプレビューの位置と大きさ（表示しないときは ""）
+// 大きさは左右なら桁数、上下なら行数
+func (m *Model) previewPane() (string, int) {
+	// スコア内訳やヘルプの表示中、狭いときはプレビューを出さない
+	if !m.config.EnablePreview || m.previewHidden || m.explain || m.help {
+		return "", 0
+	}
+
+	// 3列表示ではプレビューは常に右の列
+	if m.columns {
+		if m.width < minPreviewWidth {
+			return "", 0
+		}
+		return "right", m.width * previewColumnRatio / 100
+	}
+
+	body := m.bodyHeight()
+	switch pos := m.config.Layout.PreviewPosition; pos {
+	case "top", "bottom":
+		if body < minPreviewHeight {
+			return "", 0
+		}
+		// 区切り線1行とリスト1行は残す
+		return pos, max(1, min(m.previewSize.resolve(body), body-2))
+	default:
+		if m.width < minPreviewWidth {
+			return "", 0
+		}
+		return pos, max(1, min(m.previewSize.resolve(m.width), m.width-1-minListWidth))
+	}
+}
+
+// listHeight はファイルリストに使える行数
+func (m *Model) listHeight() int {
+	if pos, size := m.previewPane(); pos == "top" || pos == "bottom" {
+		return max(1, m.bodyHeight()-size-1)
+	}
+	return m.bodyHeight()
+}
+
+// listWidth はファイルリストに使える桁数
+func (m *Model) listWidth() int {
+	switch pos, size := m.previewPane(); pos {
+	case "left", "right":
+		return m.width - size - 1 - m.parentWidth()
+	case "top", "bottom":
+		return m.width
+	}
+	if m.columns {
+		return m.width - m.parentWidth()
+	}
+	return min(m.width, 80)
+}
+
+// separatorLine はプロンプトと本体の間の区切り線
+// 左右プレビューの境目には分岐の記号を入れる（reverse では上向き）
+func (m *Model) separatorLine(border borderStyle) string {
+	junction := border.teeDown
+	if m.config.Layout.Reverse {
+		junction = border.teeUp
+	}
+
+	pos, size := m.previewPane()
+	listWidth := m.listWidth()
+	line := strings.Repeat(border.horizontal, listWidth)
+	switch pos {
+	case "right":
+		line += junction + strings.Repeat(border.horizontal, size)
+	case "left":
+		line = strings.Repeat(border.horizontal, size) + junction + line
+	}
+	if pw := m.parentWidth(); pw > 0 {
+		line = strings.Repeat(border.horizontal, pw-1) + junction + line
+	}
+	return paint(m.colors.Border, line)
+}
+
+// renderBody は本体（リストとプレビュー）を bodyHeight 行で描画
+func (m *Model) renderBody(border borderStyle) []string {
+	pos, size := m.previewPane()
+	listWidth := m.listWidth()
+	list := m.orientedList(listWidth, m.listHeight())
+
+	// プレビュー行（先頭に1桁の余白）
+	previewWidth := size
+	if pos == "top" || pos == "bottom" {
+		previewWidth = m.width
+	}
+	previewLine := func(i int) string {
+		i += m.previewOffset
+		if i >= len(m.previewCache) {
+			return ""
+		}
+		return " " + paint(m.colors.Preview, truncateWidth(m.previewCache[i], previewWidth-1))
+	}
+
+	vertical := paint(m.colors.Border, border.vertical)
+	horizontal := paint(m.colors.Border, strings.Repeat(border.horizontal, m.width))
+
+	var lines []string
+	switch pos {
+	case "right":
+		for i, line := range list {
+			lines = append(lines, padRight(line, listWidth)+vertical+previewLine(i))
+		}
+	case "left":
+		for i, line := range list {
+			lines = append(lines, padRight(previewLine(i), size)+vertical+line)
+		}
+	case "top":
+		for i := 0; i < size; i++ {
+			lines = append(lines, previewLine(i))
+		}
+		lines = append(lines, horizontal)
+		lines = append(lines, list...)
+	case "bottom":
+		lines = append(lines, list...)
+		lines = append(lines, horizontal)
+		for i := 0; i < size; i++ {
+			lines = append(lines, previewLine(i))
+		}
+	default:
+		lines = list
+	}
+
+	// 3列表示では左に親ディレクトリの列
+	if pw := m.parentWidth(); pw > 0 {
+		parent := m.renderParent(pw-1, len(lines))
+		for i := range lines {
+			lines[i] = padRight(parent[i], pw-1) + vertical + lines[i]
+		}
+	}
+	return lines
+}
+
+// orientedList はリストを height 行に揃える
+// reverse ではプロンプトに近い下端から上へ並べる
+func (m *Model) orientedList(width, height int) []string {
+	lines := m.renderList(width, height)
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+	if m.config.Layout.Reverse {
+		for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+			lines[i], lines[j] = lines[j], lines[i]
+		}
+	}
+	return lines
+}
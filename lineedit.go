@@ -0,0 +1,118 @@
+package main
+
+import "unicode"
+
+// クエリ行の編集（readline 風）
+// m.queryPos はクエリ内のカーソル位置（文字単位）
+
+// setQuery はクエリとカーソル位置を更新し、変わっていればフィルタを更新
+func (m *Model) setQuery(runes []rune, pos int) {
+	query := string(runes)
+	m.queryPos = max(0, min(pos, len(runes)))
+	if query != m.query {
+		m.query = query
+		m.updateFilter()
+	}
+}
+
+// insertText はカーソル位置に文字列を挿入
+func (m *Model) insertText(text string) {
+	runes := []rune(m.query)
+	ins := []rune(text)
+	edited := make([]rune, 0, len(runes)+len(ins))
+	edited = append(edited, runes[:m.queryPos]...)
+	edited = append(edited, ins...)
+	edited = append(edited, runes[m.queryPos:]...)
+	m.setQuery(edited, m.queryPos+len(ins))
+}
+
+// deleteRange は [from, to) を削除し、kill が true ならキルバッファに入れる
+func (m *Model) deleteRange(from, to int, kill bool) {
+	runes := []rune(m.query)
+	from, to = max(0, from), min(to, len(runes))
+	if from >= to {
+		return
+	}
+	if kill {
+		m.killBuffer = string(runes[from:to])
+	}
+	edited := append(append([]rune{}, runes[:from]...), runes[to:]...)
+	m.setQuery(edited, from)
+}
+
+// moveQueryCursor はクエリ内のカーソルを移動
+func (m *Model) moveQueryCursor(pos int) {
+	m.queryPos = max(0, min(pos, len([]rune(m.query))))
+}
+
+// wordStartBefore は pos より前の単語の先頭（英数字の並びを単語とする）
+func wordStartBefore(runes []rune, pos int) int {
+	for pos > 0 && !isWordRune(runes[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordRune(runes[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+// wordEndAfter は pos より後の単語の末尾
+func wordEndAfter(runes []rune, pos int) int {
+	for pos < len(runes) && !isWordRune(runes[pos]) {
+		pos++
+	}
+	for pos < len(runes) && isWordRune(runes[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// spaceWordStartBefore は Ctrl+W 用: 空白区切りで pos より前の単語の先頭
+func spaceWordStartBefore(runes []rune, pos int) int {
+	for pos > 0 && unicode.IsSpace(runes[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !unicode.IsSpace(runes[pos-1]) {
+		pos--
+	}
+	return pos
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// handleLineEdit はクエリ編集キーを処理（処理したら true）
+func (m *Model) handleLineEdit(k Key) bool {
+	runes := []rune(m.query)
+
+	switch {
+	case m.keymap.CharLeft.Matches(k):
+		m.moveQueryCursor(m.queryPos - 1)
+	case m.keymap.CharRight.Matches(k):
+		m.moveQueryCursor(m.queryPos + 1)
+	case m.keymap.LineStart.Matches(k):
+		m.moveQueryCursor(0)
+	case m.keymap.LineEnd.Matches(k):
+		m.moveQueryCursor(len(runes))
+	case m.keymap.WordLeft.Matches(k):
+		m.moveQueryCursor(wordStartBefore(runes, m.queryPos))
+	case m.keymap.WordRight.Matches(k):
+		m.moveQueryCursor(wordEndAfter(runes, m.queryPos))
+	case m.keymap.Backspace.Matches(k):
+		m.deleteRange(m.queryPos-1, m.queryPos, false)
+	case m.keymap.DeleteChar.Matches(k):
+		m.deleteRange(m.queryPos, m.queryPos+1, false)
+	case m.keymap.DeleteWord.Matches(k):
+		m.deleteRange(spaceWordStartBefore(runes, m.queryPos), m.queryPos, true)
+	case m.keymap.KillLineStart.Matches(k):
+		m.deleteRange(0, m.queryPos, true)
+	case m.keymap.KillLineEnd.Matches(k):
+		m.deleteRange(m.queryPos, len(runes), true)
+	case m.keymap.Yank.Matches(k):
+		m.insertText(m.killBuffer)
+	default:
+		return false
+	}
+	return true
+}
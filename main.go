@@ -1,18 +1,39 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"unsafe"
 )
 
 func main() {
+	explain := flag.Bool("explain", false, "スコア内訳を表示する")
+	print0 := flag.Bool("print0", false, "パスを改行ではなく NUL 区切りで出力する")
+	heightSpec := flag.String("height", "", "全画面ではなく画面下部の N 行（または N%）に表示する")
+	previewPos := flag.String("preview-position", "", "プレビューの位置（right/left/top/bottom）")
+	previewSize := flag.String("preview-size", "", "プレビューの大きさ（50% または桁数/行数）")
+	reverse := flag.Bool("reverse", false, "プロンプトを下にしてリストを下から上へ並べる")
+	border := flag.String("border", "", "区切り線（sharp/thick/double/ascii/none）")
+	iconSet := flag.String("icons", "", "アイコンセット（emoji/nerd/ascii/none）")
+	flag.Parse()
+
+	if *heightSpec != "" {
+		if _, err := parseHeight(*heightSpec, minHeight); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	inline := *heightSpec != ""
+
 	// 起動ディレクトリ取得
 	startDir := "."
-	if len(os.Args) > 1 {
-		startDir = os.Args[1]
+	if flag.NArg() > 0 {
+		startDir = flag.Arg(0)
 	}
 
 	// モデル初期化
@@ -21,6 +42,44 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	model.explain = *explain
+
+	// 設定ファイルの誤りは標準エラーにも残す（全画面モードでは終了後に見える）
+	if model.configErr != nil {
+		for _, line := range strings.Split(model.configErr.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "Warning: config: %s\n", line)
+		}
+	}
+
+	// アイコンとレイアウトはフラグで設定ファイルを上書き
+	if *iconSet != "" {
+		icons := model.config.Icons
+		icons.Set = *iconSet
+		if err := icons.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
+		model.config.Icons = icons
+		model.icons = NewIcons(icons)
+	}
+
+	layout := model.config.Layout
+	if *previewPos != "" {
+		layout.PreviewPosition = *previewPos
+	}
+	if *previewSize != "" {
+		layout.PreviewSize = *previewSize
+	}
+	if *reverse {
+		layout.Reverse = true
+	}
+	if *border != "" {
+		layout.Border = *border
+	}
+	if err := model.SetLayout(layout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
 
 	// /dev/ttyを開く（パイプライン対応）
 	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
@@ -37,63 +96,140 @@ func main() {
 		os.Exit(1)
 	}
 
-	// 画面クリア & カーソル非表示
-	fmt.Fprint(tty, "\033[2J\033[H\033[?25l")
+	// 全画面なら代替スクリーンに切り替え（終了時に元の画面内容が戻る）
+	// カーソル非表示 & ブラケットペースト有効化
+	if !inline {
+		fmt.Fprint(tty, "\033[?1049h\033[2J\033[H")
+	}
+	fmt.Fprint(tty, "\033[?25l\033[?2004h")
+
+	// マウス報告（SGR 形式）を有効化
+	// インラインモードでは描画領域の画面上の位置が分からずクリック位置を解釈できないので使わない
+	mouse := model.config.Mouse && !inline
+	if mouse {
+		fmt.Fprint(tty, "\033[?1000h\033[?1006h")
+	}
 
-	// 初期描画
-	renderToTTY(model, tty)
+	// 端末サイズは stdout ではなく tty から取る（$(fuzzy-filer) でも正しいサイズ）
+	width, height := viewSize(int(tty.Fd()), *heightSpec)
+	model.Resize(width, height)
+
+	// 差分描画レンダラー
+	renderer := NewRenderer(tty, width, height, supportsSyncOutput(model.config.SyncOutput), inline)
+
+	// ウィンドウサイズ変更の通知
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+
+	// 初期描画（設定ファイルの誤りは最初のキー入力まで最終行に出す）
+	renderToTTY(model, renderer)
+	if model.configErr != nil {
+		msg := strings.ReplaceAll(model.configErr.Error(), "\n", "; ")
+		renderer.Notice(paint(model.colors.Error, "Config: "+msg))
+	}
 
 	// メインループ
-	reader := bufio.NewReader(tty)
-	var selectedPath string
+	keys := NewKeyReader(tty)
+	var selectedPaths []string
 
+loop:
 	for {
-		r, _, err := reader.ReadRune()
-		if err != nil {
-			break
+		var key Key
+		select {
+		case <-winch:
+			// サイズ変更: レイアウトを計算し直して即再描画
+			width, height := viewSize(int(tty.Fd()), *heightSpec)
+			model.Resize(width, height)
+			renderer.Resize(width, height)
+			renderToTTY(model, renderer)
+			continue
+		case k, ok := <-keys.Keys():
+			if !ok {
+				break loop
+			}
+			key = k
 		}
 
 		// 入力処理
-		quit, path, err := model.HandleInput(r)
+		quit, paths, err := model.HandleInput(key)
 		if err != nil {
-			// エラーを画面に表示して継続
-			fmt.Fprintf(tty, "\n\033[1;31mError: %v\033[0m\n", err)
+			// 画面を描き直してからエラーを最終行に重ねて表示（次の描画で上書きされる）
+			renderToTTY(model, renderer)
+			renderer.Notice(paint(model.colors.Error, fmt.Sprintf("Error: %v", err)))
 			continue
 		}
 
 		if quit {
-			selectedPath = path
-			break
+			selectedPaths = paths
+			break loop
 		}
 
-		// 再描画
-		renderToTTY(model, tty)
+		// 再描画（IME などでまとめて届いた入力は全部処理してから描画）
+		if !keys.Buffered() {
+			renderToTTY(model, renderer)
+		}
 	}
 
 	// 終了時クリーンアップ
-	// 1. 画面クリア
-	fmt.Fprint(tty, "\033[2J\033[H")
+	// 1. 描画領域をクリアし、全画面なら元の画面に戻す
+	renderer.Clear()
+	if !inline {
+		fmt.Fprint(tty, "\033[?1049l")
+	}
 
 	// 2. ターミナル状態を復元
 	restoreTerminalForFd(int(tty.Fd()), oldState)
 
-	// 3. カーソル表示
-	fmt.Fprint(tty, "\033[?25h")
+	// 3. カーソル表示 & ブラケットペースト・マウス報告無効化
+	fmt.Fprint(tty, "\033[?25h\033[?2004l")
+	if mouse {
+		fmt.Fprint(tty, "\033[?1006l\033[?1000l")
+	}
 
 	// 4. パスを標準出力に出力（ttyではなくstdout）
-	if selectedPath != "" {
-		fmt.Println(selectedPath)
+	separator := "\n"
+	if *print0 {
+		separator = "\x00"
+	}
+	for _, path := range selectedPaths {
+		fmt.Print(path + separator)
+	}
+}
+
+// viewSize は描画に使う幅と高さ（--height 指定時は画面下部の行数）
+func viewSize(fd int, heightSpec string) (int, int) {
+	width, height := getTerminalSize(fd)
+	if heightSpec == "" {
+		return width, height
+	}
+	rows, _ := parseHeight(heightSpec, height)
+	return width, min(height, max(minHeight, rows))
+}
+
+// parseHeight は --height の値（"20" や "40%"）を端末の高さに対する行数にする
+func parseHeight(spec string, termHeight int) (int, error) {
+	if percent, ok := strings.CutSuffix(spec, "%"); ok {
+		n, err := strconv.Atoi(percent)
+		if err != nil || n <= 0 || n > 100 {
+			return 0, fmt.Errorf("invalid --height: %q (1%%〜100%%)", spec)
+		}
+		return termHeight * n / 100, nil
+	}
+
+	n, err := strconv.Atoi(spec)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid --height: %q (行数か割合)", spec)
 	}
+	return n, nil
 }
 
-// renderToTTY は画面を再描画（tty出力版）
-func renderToTTY(m *Model, tty *os.File) {
-	// カーソルを左上に移動して描画
-	// 	fmt.Fprint(tty, "\033[H")
-	// 	fmt.Fprint(tty, m.View())
-	// fmt.Fprint(tty, "\033[K") // 行末までクリア
-	fmt.Fprint(tty, "\033[2J\033[H")
-	fmt.Fprint(tty, m.View())
+// renderToTTY は画面を再描画（前回との差分だけ tty に出力）
+func renderToTTY(m *Model, renderer *Renderer) {
+	// キャレットは View が描くので端末のカーソルは非表示のまま入力位置に置く
+	// （IME の変換候補がここに出る。全角文字の幅も考慮）
+	row, col := m.CursorPosition()
+	renderer.Render(m.View(), row, col)
 }
 
 // setRawModeForFd は指定fdをrawモードに設定
@@ -107,7 +243,7 @@ func setRawModeForFd(fd int) (*syscall.Termios, error) {
 	}
 
 	newState := *oldState
-	newState.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG
+	newState.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
 	newState.Iflag &^= syscall.IXON | syscall.ICRNL
 	newState.Cc[syscall.VMIN] = 1
 	newState.Cc[syscall.VTIME] = 0
@@ -0,0 +1,69 @@
+package main
+
+import "path/filepath"
+
+// 複数選択（マーク）
+// マークは絶対パスで持つので、クエリやディレクトリを変えても残る
+
+// absPath はエントリの絶対パス
+func (m *Model) absPath(entry FileEntry) string {
+	return filepath.Join(m.currentDir, entry.Path)
+}
+
+// isMarked はエントリがマークされているか
+func (m *Model) isMarked(entry FileEntry) bool {
+	return m.marked[m.absPath(entry)]
+}
+
+// setMark はマークを付け外しする（付けた順を保つ）
+func (m *Model) setMark(entry FileEntry, on bool) {
+	path := m.absPath(entry)
+	if m.marked[path] == on {
+		return
+	}
+
+	if on {
+		m.marked[path] = true
+		m.markOrder = append(m.markOrder, path)
+		return
+	}
+
+	delete(m.marked, path)
+	for i, p := range m.markOrder {
+		if p == path {
+			m.markOrder = append(m.markOrder[:i], m.markOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// toggleMark はカーソル位置のマークを切り替えて delta 移動
+func (m *Model) toggleMark(delta int) {
+	if len(m.filteredEntries) == 0 {
+		return
+	}
+	entry := m.filteredEntries[m.cursor].Entry
+	m.setMark(entry, !m.isMarked(entry))
+	m.moveCursor(delta)
+}
+
+// markAll は表示中の一覧をすべてマーク
+func (m *Model) markAll() {
+	for _, scored := range m.filteredEntries {
+		m.setMark(scored.Entry, true)
+	}
+}
+
+// invertMarks は表示中の一覧のマークを反転
+func (m *Model) invertMarks() {
+	for _, scored := range m.filteredEntries {
+		m.setMark(scored.Entry, !m.isMarked(scored.Entry))
+	}
+}
+
+// markedPaths はマークしたパス（マークした順）
+func (m *Model) markedPaths() []string {
+	paths := make([]string, len(m.markOrder))
+	copy(paths, m.markOrder)
+	return paths
+}
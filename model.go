@@ -1,10 +1,15 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"syscall"
+	"time"
+	"unicode"
 	"unsafe"
 )
 
@@ -12,14 +17,42 @@ import (
 type Model struct {
 	currentDir      string
 	allEntries      []FileEntry
-	filteredEntries []FileEntry
+	filteredEntries []ScoredEntry
 	query           string
+	queryPos        int       // クエリ内のカーソル位置（文字単位）
+	killBuffer      string    // Ctrl+W/U/K で消した文字列（Ctrl+Y で戻す）
+	regexMode       bool      // クエリを正規表現として扱う
+	queryErr        string    // クエリのエラー（プロンプトに表示）
+	truncated       bool      // マッチが多すぎて maxResults 件で切った
+	configErr       error     // 設定ファイルの誤り（起動時に表示）
+	explain         bool      // スコア内訳オーバーレイ表示
+	sortOrder       SortOrder // 一覧の並び順
 	cursor          int
+	offset          int // リストのスクロール位置（行）
 	keymap          KeyMap
 	config          Config
 	width           int
 	height          int
-	previewCache    []string // プレビュー内容キャッシュ
+	previewCache    []string        // プレビュー内容キャッシュ
+	previewHidden   bool            // 実行中にプレビューを隠した
+	previewOffset   int             // プレビューのスクロール位置（行）
+	previewSize     PaneSize        // プレビューの大きさ（実行中に変更できる）
+	colors          Theme           // SGR パラメータに変換済みの配色
+	lsColors        LSColors        // LS_COLORS によるエントリの色
+	icons           Icons           // ファイルの種類ごとのアイコン
+	lastClick       time.Time       // ダブルクリック判定用の前回クリック時刻
+	lastClickIndex  int             // 前回クリックしたエントリ
+	tree            bool            // ツリー表示
+	treeNodes       []treeNode      // ツリー表示での各行の罫線など（filteredEntries と同じ並び）
+	expanded        map[string]bool // ツリー表示で展開したディレクトリ
+	columns         bool            // 3列表示（親、現在、プレビュー）
+	shallow         bool            // allEntries は3列表示用に直下だけ走査したもの
+	parentEntries   []FileEntry     // 3列表示の親ディレクトリの一覧
+	help            bool            // ヘルプ表示中
+	helpQuery       string          // ヘルプの検索語
+	helpOffset      int             // ヘルプのスクロール位置（行）
+	marked          map[string]bool // マーク済みの絶対パス
+	markOrder       []string        // マークした順（出力順）
 }
 
 // NewModel は新しいモデルを作成
@@ -30,26 +63,43 @@ func NewModel(startDir string) (*Model, error) {
 	}
 
 	// 設定読み込み
-	config := LoadConfig()
+	config, configErr := LoadConfig()
 
 	entries, err := ScanFiles(absDir, config)
 	if err != nil {
 		return nil, err
 	}
 
-	width, height := getTerminalSize()
+	// 不明なアクション名はそれだけ無視し、設定ファイルの誤りとして表示する
+	keymap := DefaultKeyMap()
+	if err := keymap.Apply(config.Keys); err != nil {
+		configErr = errors.Join(configErr, err)
+	}
 
 	m := &Model{
 		currentDir:      absDir,
 		allEntries:      entries,
-		filteredEntries: RankEntries(entries, ""),
+		filteredEntries: RankEntries(entries, "", config.Ranking, SortOrder{}),
 		query:           "",
 		cursor:          0,
-		keymap:          DefaultKeyMap(),
+		keymap:          keymap,
 		config:          config,
-		width:           width,
-		height:          height,
+		width:           80, // Resize で端末サイズに合わせる
+		height:          24,
 		previewCache:    nil,
+		marked:          make(map[string]bool),
+		expanded:        make(map[string]bool),
+		configErr:       configErr,
+	}
+	m.SetLayout(config.Layout)
+
+	m.icons = NewIcons(config.Icons)
+
+	// 配色（NO_COLOR なら色を使わない）
+	noColor := noColorRequested()
+	m.colors = ResolveTheme(config.Theme, config.Colors, detectColorDepth(), noColor)
+	if config.LSColors && !noColor {
+		m.lsColors = ParseLSColors(os.Getenv("LS_COLORS"))
 	}
 
 	// 初期プレビュー生成
@@ -60,7 +110,48 @@ func NewModel(startDir string) (*Model, error) {
 
 // updateFilter はクエリに基づいてフィルタ更新
 func (m *Model) updateFilter() {
-	m.filteredEntries = RankEntries(m.allEntries, m.query)
+	// size:>10M などのメタデータ条件で先に絞り込む
+	// 正規表現モードではクエリ全体がパターン（空白や "key:" もそのまま使う）
+	text, preds := m.query, []Predicate(nil)
+	if !m.regexMode {
+		var err error
+		text, preds, err = ParseQuery(m.query, time.Now())
+		if err != nil {
+			// 不正な条件は結果を消さずにエラーだけ表示
+			m.queryErr = err.Error()
+			m.syncTree()
+			return
+		}
+	}
+	// 3列表示では現在のディレクトリ直下だけを対象にする
+	entries := m.allEntries
+	if m.columns {
+		entries = directChildren(entries)
+	}
+	candidates := FilterEntries(entries, preds)
+
+	if m.regexMode && text != "" {
+		re, err := regexp.Compile(text)
+		if err != nil {
+			// 不正なパターンは結果を消さずにエラーだけ表示
+			m.queryErr = strings.TrimPrefix(err.Error(), "error parsing regexp: ")
+			m.syncTree()
+			return
+		}
+		m.queryErr = ""
+		m.filteredEntries = RankEntriesRegex(candidates, re, m.config.Ranking, m.sortOrder)
+	} else {
+		m.queryErr = ""
+		m.filteredEntries = RankEntries(candidates, text, m.config.Ranking, m.sortOrder)
+	}
+	// スコア順の結果だけ上位で切る（クエリなしの一覧は最後まで辿れるよう全件）
+	m.truncated = text != "" && len(m.filteredEntries) > maxResults
+	if m.truncated {
+		m.filteredEntries = m.filteredEntries[:maxResults]
+	}
+	if m.tree {
+		m.buildTree(text != "" || len(preds) > 0)
+	}
 	if m.cursor >= len(m.filteredEntries) {
 		m.cursor = max(0, len(m.filteredEntries)-1)
 	}
@@ -74,218 +165,506 @@ func (m *Model) updatePreview() {
 		return
 	}
 
-	selected := m.filteredEntries[m.cursor]
+	selected := m.filteredEntries[m.cursor].Entry
 	fullPath := filepath.Join(m.currentDir, selected.Path)
 	m.previewCache = GeneratePreview(fullPath, m.config.PreviewLines)
+	m.previewOffset = 0
 }
 
-// changeDirectory はディレクトリ変更
+// changeDirectory はディレクトリ変更（newDir は現在のディレクトリからの相対パスか絶対パス）
 func (m *Model) changeDirectory(newDir string) error {
-	absDir := filepath.Join(m.currentDir, newDir)
-	entries, err := ScanFiles(absDir, m.config)
+	absDir := newDir
+	if !filepath.IsAbs(newDir) {
+		absDir = filepath.Join(m.currentDir, newDir)
+	}
+	// 3列表示では直下しか使わないので1階層だけ走査する
+	config := m.config
+	if m.columns {
+		config.MaxDepth = 1
+	}
+	entries, err := ScanFiles(absDir, config)
 	if err != nil {
 		return err
 	}
 
 	m.currentDir = absDir
 	m.allEntries = entries
+	m.shallow = m.columns
 	m.query = ""
+	m.queryPos = 0
 	m.cursor = 0
+	m.offset = 0
+	m.expanded = make(map[string]bool)
+	if m.columns {
+		m.loadParent()
+	}
 	m.updateFilter()
 	return nil
 }
 
+// Resize は端末サイズの変更を反映
+func (m *Model) Resize(width, height int) {
+	m.width = width
+	m.height = height
+}
+
 // model.go
 func (m *Model) View() string {
-	var b strings.Builder
+	// 小さすぎる端末ではレイアウトが崩れるので案内だけ出す
+	if m.width < minWidth || m.height < minHeight {
+		return truncateANSI(fmt.Sprintf("too small (%dx%d)", m.width, m.height), m.width)
+	}
 
 	// ヘッダー
-	b.WriteString(fmt.Sprintf("\033[1;36m%s\033[0m ", m.currentDir))
-	b.WriteString(fmt.Sprintf("\033[2m[%d files]\033[0m\n", len(m.allEntries)))
-	b.WriteString(fmt.Sprintf("> %s\033[K\n", m.query))
-	b.WriteString(strings.Repeat("─", min(m.width, 80)) + "\n")
+	header := paint(m.colors.Header, m.currentDir) + " "
+	header += paint(m.colors.Info, fmt.Sprintf("[%d files]", len(m.allEntries)))
+	if len(m.markOrder) > 0 {
+		header += " " + paint(m.colors.Mark, fmt.Sprintf("[%d marked]", len(m.markOrder)))
+	}
 
-	// **プレビュー有効時は左右分割**♥
-	if m.config.EnablePreview && len(m.previewCache) > 0 {
-		return m.viewWithPreview()
+	// フッター♥
+	// キー表示はキーマップから作る（設定で変えたキーもそのまま出る）
+	km := m.keymap
+	footer := paint(m.colors.Info, fmt.Sprintf("%s [Sort: %s] [%s/%s]移動 [%s]選択 [%s]マーク [%s]ヘルプ [%s]終了",
+		m.positionIndicator(), m.sortOrder, km.Down.hint(), km.Up.hint(), km.Enter.hint(),
+		km.ToggleMarkDown.hint(), km.Help.hint(), km.Quit.hint()))
+
+	border := borderStyles[m.config.Layout.Border]
+	body := m.renderBody(border)
+	if m.help {
+		body = m.renderHelp(m.width, m.bodyHeight())
 	}
 
-	// 通常表示（プレビューなし）
-	for i, entry := range m.filteredEntries {
-		cursor := "  "
-		if i == m.cursor {
-			cursor = "\033[1;33m>\033[0m "
-		}
+	// 通常は上からヘッダー、プロンプト、区切り線、本体、空行、フッター
+	// reverse ではプロンプトが最下行になるよう上下を入れ替える
+	var lines []string
+	if m.config.Layout.Reverse {
+		lines = append(lines, truncateANSI(header, m.width), truncateANSI(footer, m.width), "")
+		lines = append(lines, body...)
+		lines = append(lines, m.separatorLine(border), m.promptLine())
+	} else {
+		lines = append(lines, truncateANSI(header, m.width), m.promptLine(), m.separatorLine(border))
+		lines = append(lines, body...)
+		lines = append(lines, "", truncateANSI(footer, m.width))
+	}
 
-		icon := " "
-		color := "\033[0m"
-		if entry.IsDir {
-			icon = "📁"
-			color = "\033[1;34m"
-		} else {
-			icon = "📄"
+	return strings.Join(lines, "\n")
+}
+
+const (
+	minWidth        = 20 // これより狭いと描画しない
+	minHeight       = 6  // ヘッダー3行 + リスト1行 + フッター2行
+	minPreviewWidth = 40 // これより狭いとプレビューを出さない
+)
+
+// listRows はリストの各行が指すエントリ番号（-1 は「もしかして」の区切り行）
+func (m *Model) listRows() []int {
+	rows := make([]int, 0, len(m.filteredEntries)+1)
+	for i := range m.filteredEntries {
+		if m.startsSuggestions(i) {
+			rows = append(rows, -1)
 		}
+		rows = append(rows, i)
+	}
+	return rows
+}
 
-		displayPath := entry.Name
-		if entry.DirPath != "." {
-			displayPath = filepath.Join(entry.DirPath, entry.Name)
+// scrollToCursor はカーソルが scroll_off 行の余白を保って見えるようにオフセットを調整
+func (m *Model) scrollToCursor(rows []int, height int) {
+	cursorRow := 0
+	for r, idx := range rows {
+		if idx == m.cursor {
+			cursorRow = r
+			break
 		}
+	}
 
-		b.WriteString(fmt.Sprintf("%s %s %s%s\033[0m\n",
-			cursor, icon, color, displayPath))
+	margin := min(m.config.ScrollOff, (height-1)/2)
+	if cursorRow-margin < m.offset {
+		m.offset = cursorRow - margin
 	}
+	if cursorRow+margin >= m.offset+height {
+		m.offset = cursorRow + margin - height + 1
+	}
+	m.offset = max(0, min(m.offset, len(rows)-height))
+}
 
-	b.WriteString("\n")
-	b.WriteString("\033[2m[Ctrl+N/P]移動 [Enter]選択 [Ctrl+D]終了\033[0m")
+// renderList は表示範囲のリスト行を描画（スクロールバー付き）
+func (m *Model) renderList(width, height int) []string {
+	rows := m.listRows()
+	m.scrollToCursor(rows, height)
 
-	return b.String()
-}
+	scrollable := len(rows) > height
+	if scrollable {
+		width-- // 右端はスクロールバー
+	}
 
-// viewWithPreview は左右分割プレビュー表示♠
-// model.go
-func (m *Model) viewWithPreview() string {
-	var b strings.Builder
+	var lines []string
+	for r := m.offset; r < min(len(rows), m.offset+height); r++ {
+		if rows[r] < 0 {
+			lines = append(lines, m.suggestionHeader(width))
+			continue
+		}
+		lines = append(lines, m.entryLine(rows[r], width))
+	}
 
-	// ヘッダー
-	b.WriteString(fmt.Sprintf("\033[1;36m%s\033[0m ", m.currentDir))
-	b.WriteString(fmt.Sprintf("\033[2m[%d files]\033[0m\n", len(m.allEntries)))
-	b.WriteString(fmt.Sprintf("> %s\033[K\n", m.query))
-
-	// 区切り線
-	leftWidth := m.width / 2
-	rightWidth := m.width - leftWidth - 1
-
-	b.WriteString(strings.Repeat("─", leftWidth))
-	b.WriteString("┬")
-	b.WriteString(strings.Repeat("─", rightWidth))
-	b.WriteString("\n")
-
-	// 描画する最大行数♥
-	maxListLines := min(len(m.filteredEntries), m.height-6)
-	maxPreviewLines := len(m.previewCache)
-	maxLines := max(maxListLines, maxPreviewLines) // どちらか長い方♠
-
-	for i := 0; i < maxLines; i++ {
-		// 左側: ファイルリスト♧
-		if i < len(m.filteredEntries) {
-			entry := m.filteredEntries[i]
-			cursor := "  "
-			if i == m.cursor {
-				cursor = "\033[1;33m>\033[0m "
+	if scrollable {
+		// つまみの位置と大きさは表示範囲の割合から
+		thumbSize := max(1, height*height/len(rows))
+		thumbStart := m.offset * (height - thumbSize) / max(1, len(rows)-height)
+		for i := 0; i < height; i++ {
+			line := ""
+			if i < len(lines) {
+				line = lines[i]
 			}
-
-			icon := "📄"
-			color := "\033[0m"
-			if entry.IsDir {
-				icon = "📁"
-				color = "\033[1;34m"
+			bar := paint(m.colors.Info, "│")
+			if i >= thumbStart && i < thumbStart+thumbSize {
+				bar = paint(m.colors.Scrollbar, "┃")
 			}
-
-			displayPath := entry.Name
-			if entry.DirPath != "." {
-				displayPath = filepath.Join(entry.DirPath, entry.Name)
+			if i < len(lines) {
+				lines[i] = padRight(line, width) + bar
+			} else {
+				lines = append(lines, padRight(line, width)+bar)
 			}
+		}
+	}
 
-			cursorWidth := 2 // "  " or "> " どちらも2文字♥
-			iconWidth := 2   // 絵文字は2文字幅♧
-			spaceWidth := 1  // アイコンと名前の間
+	return lines
+}
 
-			// 表示幅 = カーソル + アイコン + スペース + パス♠
-			visibleLen := cursorWidth + iconWidth + spaceWidth + len(displayPath)
+// entryLine はエントリ1行を width 文字に収めて描画
+func (m *Model) entryLine(i, width int) string {
+	scored := m.filteredEntries[i]
+	entry := scored.Entry
 
-			// 切り詰め処理（変更なし）♥
-			if visibleLen > leftWidth-1 {
-				overflow := visibleLen - (leftWidth - 4)
-				if overflow > 0 && len(displayPath) > overflow {
-					displayPath = displayPath[:len(displayPath)-overflow] + "..."
-				}
-			}
+	// カーソル1文字 + マーク1文字
+	cursor := " "
+	if i == m.cursor {
+		cursor = paint(m.colors.Cursor, ">")
+	}
+	if m.isMarked(entry) {
+		cursor += paint(m.colors.Mark, "*")
+	} else {
+		cursor += " "
+	}
 
-			line := fmt.Sprintf("%s%s %s%s\033[0m", cursor, icon, color, displayPath)
+	icon := m.icons.Icon(entry)
+	color := sgr(m.entryStyle(entry))
+
+	// ツリー表示では罫線と展開状態の記号を付けてファイル名だけを出す
+	displayPath := entry.Path
+	spans := scored.Spans
+	tree := ""
+	if m.tree && i < len(m.treeNodes) {
+		node := m.treeNodes[i]
+		marker := "  "
+		if entry.IsDir && node.expanded {
+			marker = "▾ "
+		} else if entry.IsDir {
+			marker = "▸ "
+		}
+		tree = node.guide + marker
+		displayPath = entry.Name
+		spans = shiftSpans(spans, len(entry.Path)-len(entry.Name))
+		if node.context {
+			color = sgr(m.colors.Info) // 祖先として出しているだけのエントリは薄く
+		}
+	}
 
-			b.WriteString(line)
+	explain := ""
+	if m.explain && len(scored.Terms) > 0 {
+		explain = fmt.Sprintf("  [%s]", scored.Terms)
+	}
 
-			// ★パディング計算を修正★♧
-			// 切り詰め後の実際の表示幅を再計算♠
-			actualVisible := cursorWidth + iconWidth + spaceWidth + len(displayPath)
-			padding := leftWidth - actualVisible
-			if padding > 0 {
-				b.WriteString(strings.Repeat(" ", padding))
-			}
+	cursorWidth := 2 // カーソル + マーク で2文字♥
+
+	// 表示幅 = カーソル + アイコン（名前との間の空白を含む） + パス♠
+	// バイト数ではなく表示幅で数え、書記素クラスタの途中では切らない
+	available := width - cursorWidth - stringWidth(icon) - stringWidth(explain)
+	if tree != "" {
+		// 深い階層でも罫線だけで幅を使い切らないよう、名前の分（最大で半分）を残して切る
+		tree = truncatePrefix(tree, max(0, available-min(stringWidth(displayPath), available/2)))
+		available -= stringWidth(tree)
+	}
+	suffix := ""
+	if stringWidth(displayPath) > available {
+		// 先頭を残すのでハイライト位置はそのまま使える
+		// "..." すら入らない幅では省略記号を付けない
+		if available < len(ellipsis) {
+			displayPath = truncatePrefix(displayPath, max(0, available))
 		} else {
-			// ファイルリストが終わったら空白♠
-			b.WriteString(strings.Repeat(" ", leftWidth))
+			displayPath = truncatePrefix(displayPath, available-len(ellipsis))
+			suffix = ellipsis
 		}
+	}
 
-		b.WriteString("│")
+	highlighted := highlightSpans(displayPath, spans, m.colors.Match, color)
+	line := fmt.Sprintf("%s%s%s%s%s%s\033[0m", cursor, paint(m.colors.Border, tree), icon, color, highlighted, suffix)
+	if explain != "" {
+		line += paint(m.colors.Info, explain)
+	}
+	// アイコンや内訳だけで幅を超える狭いペインでもはみ出さない
+	return truncateANSI(line, width)
+}
 
-		// 右側: プレビュー（独立したインデックス）♥
-		if i < len(m.previewCache) {
-			previewLine := m.previewCache[i]
+// positionIndicator はフッター用の現在位置表示 "[12/340]"
+func (m *Model) positionIndicator() string {
+	if len(m.filteredEntries) == 0 {
+		return "[0/0]"
+	}
+	if m.truncated {
+		return fmt.Sprintf("[%d/%d+]", m.cursor+1, len(m.filteredEntries))
+	}
+	return fmt.Sprintf("[%d/%d]", m.cursor+1, len(m.filteredEntries))
+}
 
-			// 右側の幅に収める♧
-			if len(previewLine) > rightWidth-2 {
-				previewLine = previewLine[:rightWidth-5] + "..."
-			}
+// moveCursor はカーソルを delta 移動（範囲内に収める）
+func (m *Model) moveCursor(delta int) {
+	m.moveCursorTo(m.cursor + delta)
+}
 
-			b.WriteString(" " + previewLine)
-		}
+// moveCursorTo はカーソルを指定位置へ移動
+func (m *Model) moveCursorTo(pos int) {
+	pos = max(0, min(pos, len(m.filteredEntries)-1))
+	if pos != m.cursor {
+		m.cursor = pos
+		m.updatePreview()
+	}
+}
 
-		b.WriteString("\033[K\n") // 行末クリア追加♠
+// entryStyle はエントリの色（LS_COLORS があればそちらを優先）
+func (m *Model) entryStyle(entry FileEntry) string {
+	if style := m.lsColors.Style(entry); style != "" {
+		return style
+	}
+	if entry.IsDir {
+		return m.colors.Directory
 	}
+	return m.colors.File
+}
 
-	// フッター♥
-	b.WriteString("\n")
-	b.WriteString("\033[2m[Ctrl+N/P]移動 [Enter]選択 [Ctrl+D]終了 [Preview: ON]\033[0m")
+// startsSuggestions は i 番目が「もしかして」候補の先頭か
+func (m *Model) startsSuggestions(i int) bool {
+	return m.filteredEntries[i].Suggested && (i == 0 || !m.filteredEntries[i-1].Suggested)
+}
+
+// suggestionHeader は「もしかして」候補の区切り行
+func (m *Model) suggestionHeader(width int) string {
+	label := "── did you mean "
+	return paint(m.colors.Suggestion, label+strings.Repeat("─", max(0, width-stringWidth(label))))
+}
+
+// promptLine はクエリ入力行（モード表示とエラー付き）
+func (m *Model) promptLine() string {
+	if m.help {
+		return m.helpPromptLine()
+	}
 
+	prompt := "> "
+	if m.regexMode {
+		prompt = paint(m.colors.Prompt, "re>") + " "
+	}
+
+	// カーソル位置の文字を反転表示（末尾なら空白を反転）
+	runes := []rune(m.query)
+	caret := " "
+	after := ""
+	if m.queryPos < len(runes) {
+		caret = string(runes[m.queryPos])
+		after = string(runes[m.queryPos+1:])
+	}
+	line := prompt + string(runes[:m.queryPos]) + "\033[7m" + caret + "\033[0m" + after
+	if m.queryErr != "" {
+		line += "  " + paint(m.colors.Error, "✗ "+m.queryErr)
+	}
+	return line
+}
+
+// CursorPosition はクエリ入力位置の画面座標（1始まり）
+// 端末のカーソルをここに置くと IME の変換候補が入力位置に出る
+func (m *Model) CursorPosition() (int, int) {
+	prompt := 2 // "> "
+	if m.regexMode {
+		prompt = 4 // "re> "
+	}
+	row := 2
+	if m.config.Layout.Reverse {
+		row = m.height
+	}
+	if m.help {
+		return row, 1 + stringWidth(helpPrompt) + stringWidth(m.helpQuery)
+	}
+	return row, 1 + prompt + stringWidth(string([]rune(m.query)[:m.queryPos]))
+}
+
+// highlightSpans はマッチ範囲を style で強調表示する（restore は範囲外に戻すエスケープシーケンス）
+func highlightSpans(s string, spans [][]int, style, restore string) string {
+	if len(spans) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, span := range spans {
+		start, end := span[0], min(span[1], len(s))
+		if start < pos || start >= end {
+			continue
+		}
+		b.WriteString(s[pos:start])
+		b.WriteString(sgr(style))
+		b.WriteString(s[start:end])
+		b.WriteString(restore)
+		pos = end
+	}
+	b.WriteString(s[pos:])
 	return b.String()
 }
 
+// accept はカーソル位置のエントリを選ぶ（ディレクトリなら移動）
+func (m *Model) accept() (bool, []string, error) {
+	if len(m.filteredEntries) == 0 {
+		return false, nil, nil
+	}
+
+	selected := m.filteredEntries[m.cursor].Entry
+	if selected.IsDir {
+		// ディレクトリドリルダウン
+		return false, nil, m.changeDirectory(selected.Path)
+	}
+	// マークがあればマークしたパスすべて、なければ選択したファイル
+	if len(m.markOrder) > 0 {
+		return true, m.markedPaths(), nil
+	}
+	return true, []string{m.absPath(selected)}, nil
+}
+
 // HandleInput は入力処理
-func (m *Model) HandleInput(r rune) (bool, string, error) {
+func (m *Model) HandleInput(k Key) (bool, []string, error) {
+	if m.help {
+		m.handleHelpInput(k)
+		return false, nil, nil
+	}
+
 	switch {
-	case r == m.keymap.Quit:
-		return true, "", nil // 終了
+	case m.keymap.Quit.Matches(k):
+		return true, nil, nil // 終了
 
-	case r == m.keymap.Down:
-		if m.cursor < len(m.filteredEntries)-1 {
-			m.cursor++
-			m.updatePreview()
-		}
+	case m.keymap.Help.Matches(k) && (m.query == "" || !k.IsText()):
+		// "?" のような文字キーはクエリ入力中なら普通の文字として扱う
+		m.openHelp()
 
-	case r == m.keymap.Up:
-		if m.cursor > 0 {
-			m.cursor--
-			m.updatePreview()
-		}
+	case m.keymap.Down.Matches(k):
+		m.moveCursor(1)
 
-	case r == m.keymap.Enter:
-		if len(m.filteredEntries) > 0 {
-			selected := m.filteredEntries[m.cursor]
-			if selected.IsDir {
-				// ディレクトリドリルダウン
-				return false, "", m.changeDirectory(selected.Path)
-			}
-			// ファイル選択: パスを返す
-			fullPath := filepath.Join(m.currentDir, selected.Path)
-			return true, fullPath, nil
-		}
+	case m.keymap.Up.Matches(k):
+		m.moveCursor(-1)
+
+	case m.keymap.PageDown.Matches(k):
+		m.moveCursor(m.listHeight())
+
+	case m.keymap.PageUp.Matches(k):
+		m.moveCursor(-m.listHeight())
+
+	case m.keymap.HalfPageDown.Matches(k):
+		m.moveCursor(m.listHeight() / 2)
+
+	case m.keymap.HalfPageUp.Matches(k):
+		m.moveCursor(-m.listHeight() / 2)
 
-	case r == m.keymap.Backspace || r == m.keymap.DeleteQuery:
-		if len(m.query) > 0 {
-			m.query = m.query[:len(m.query)-1]
-			m.updateFilter()
+	case m.keymap.First.Matches(k):
+		m.moveCursorTo(0)
+
+	case m.keymap.Last.Matches(k):
+		m.moveCursorTo(len(m.filteredEntries) - 1)
+
+	case m.keymap.Enter.Matches(k):
+		return m.accept()
+
+	case m.keymap.AcceptMarked.Matches(k):
+		// ディレクトリだけをマークした場合もこれで確定できる
+		if len(m.markOrder) > 0 {
+			return true, m.markedPaths(), nil
 		}
 
+	case m.keymap.ToggleMarkDown.Matches(k):
+		m.toggleMark(1)
+
+	case m.keymap.ToggleMarkUp.Matches(k):
+		m.toggleMark(-1)
+
+	case m.keymap.MarkAll.Matches(k):
+		m.markAll()
+
+	case m.keymap.InvertMarks.Matches(k):
+		m.invertMarks()
+
+	case m.keymap.ToggleRegex.Matches(k):
+		m.regexMode = !m.regexMode
+		m.updateFilter()
+
+	case m.keymap.ToggleExplain.Matches(k):
+		m.explain = !m.explain
+
+	case m.keymap.TogglePreview.Matches(k):
+		m.previewHidden = !m.previewHidden
+
+	case m.keymap.PreviewLarger.Matches(k):
+		m.previewSize = m.previewSize.resize(1)
+
+	case m.keymap.PreviewSmaller.Matches(k):
+		m.previewSize = m.previewSize.resize(-1)
+
+	case m.keymap.CycleSort.Matches(k):
+		m.sortOrder = m.sortOrder.Next()
+		m.updateFilter()
+
+	case m.keymap.ReverseSort.Matches(k):
+		m.sortOrder.Desc = !m.sortOrder.Desc
+		m.updateFilter()
+
+	case m.keymap.ToggleTree.Matches(k):
+		m.toggleTree()
+
+	// ←→ はクエリ入力中ならクエリ内のカーソル移動
+	case m.tree && m.query == "" && m.keymap.Expand.Matches(k):
+		m.expandTree()
+
+	case m.tree && m.query == "" && m.keymap.Collapse.Matches(k):
+		m.collapseTree()
+
+	case m.keymap.ToggleColumns.Matches(k):
+		m.toggleColumns()
+
+	case m.columns && m.keymap.ParentDir.Matches(k) && (m.query == "" || !m.keymap.CharLeft.Matches(k)):
+		return false, nil, m.goParent()
+
+	case m.columns && m.keymap.ChildDir.Matches(k) && (m.query == "" || !m.keymap.CharRight.Matches(k)):
+		return false, nil, m.goChild()
+
+	case k.Code == KeyMouse:
+		return m.handleMouse(k.Mouse)
+
+	case k.Code == KeyPaste:
+		// 貼り付けは改行などの制御文字を除いてカーソル位置に挿入
+		m.insertText(strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, k.Paste))
+
 	default:
-		// 通常文字: クエリに追加
-		if r >= 32 && r < 127 {
-			m.query += string(r)
-			m.updateFilter()
+		// クエリ行の編集（カーソル移動、削除、キル/ヤンク）
+		if m.handleLineEdit(k) {
+			break
+		}
+		// 通常文字: カーソル位置に挿入
+		if k.IsText() {
+			m.insertText(string(k.Rune))
 		}
 	}
 
-	return false, "", nil
+	return false, nil, nil
 }
 
 func max(a, b int) int {
@@ -295,8 +674,8 @@ func max(a, b int) int {
 	return b
 }
 
-// getTerminalSize はターミナルサイズを取得
-func getTerminalSize() (int, int) {
+// getTerminalSize は fd の端末サイズを取得（stdout がパイプでも /dev/tty なら正しく取れる）
+func getTerminalSize(fd int) (int, int) {
 	type winsize struct {
 		Row    uint16
 		Col    uint16
@@ -305,12 +684,12 @@ func getTerminalSize() (int, int) {
 	}
 
 	ws := &winsize{}
-	retCode, _, _ := syscall.Syscall(syscall.SYS_IOCTL,
-		uintptr(syscall.Stdout),
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL,
+		uintptr(fd),
 		uintptr(syscall.TIOCGWINSZ),
 		uintptr(unsafe.Pointer(ws)))
 
-	if int(retCode) == -1 {
+	if errno != 0 || ws.Col == 0 || ws.Row == 0 {
 		return 80, 24 // デフォルト値
 	}
 	return int(ws.Col), int(ws.Row)
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newTestModel はファイルを走査せずにエントリを渡してモデルを作る
+func newTestModel(paths ...string) *Model {
+	config := DefaultConfig()
+	config.EnablePreview = false
+	m := &Model{
+		currentDir: "/tmp/project",
+		allEntries: testEntries(paths...),
+		keymap:     DefaultKeyMap(),
+		config:     config,
+		width:      80,
+		height:     24,
+		marked:     make(map[string]bool),
+		expanded:   make(map[string]bool),
+	}
+	m.SetLayout(config.Layout)
+	m.colors = ResolveTheme(config.Theme, Theme{}, color16, true)
+	m.updateFilter()
+	return m
+}
+
+// paths はモデルの一覧に出ているパス
+func (m *Model) paths() []string {
+	paths := make([]string, len(m.filteredEntries))
+	for i, scored := range m.filteredEntries {
+		paths[i] = scored.Entry.Path
+	}
+	return paths
+}
+
+func TestRegexModeKeepsWholeQuery(t *testing.T) {
+	// 正規表現モードでは空白も "key:" もパターンの一部
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`a  b`, "docs/a  b.txt"},
+		{`^size:`, "size:notes.txt"},
+		{`ext:go$`, "notes/ext:go"},
+	}
+
+	for _, tt := range tests {
+		m := newTestModel("docs/a  b.txt", "docs/a b.txt", "size:notes.txt", "notes/ext:go", "main.go")
+		m.regexMode = true
+		m.query = tt.query
+		m.updateFilter()
+		if m.queryErr != "" {
+			t.Errorf("%q: unexpected error %q", tt.query, m.queryErr)
+			continue
+		}
+		if got := m.paths(); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("%q matched %v, want [%s]", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLastReachesEndOfLongList(t *testing.T) {
+	paths := make([]string, maxResults+500)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("file%04d.txt", i)
+	}
+	m := newTestModel(paths...)
+
+	// クエリなしの一覧は件数で切らない
+	m.HandleInput(Key{Code: KeyEnd})
+	if want := len(paths) - 1; m.cursor != want {
+		t.Errorf("cursor after End = %d, want %d", m.cursor, want)
+	}
+	if got, want := m.positionIndicator(), fmt.Sprintf("[%d/%d]", len(paths), len(paths)); got != want {
+		t.Errorf("positionIndicator = %q, want %q", got, want)
+	}
+
+	// スコア順の結果は上位で切り、切ったことを表示する
+	m.query = "file"
+	m.updateFilter()
+	if got := len(m.filteredEntries); got != maxResults {
+		t.Errorf("len(filteredEntries) = %d, want %d", got, maxResults)
+	}
+	if got, want := m.positionIndicator(), fmt.Sprintf("[%d/%d+]", m.cursor+1, maxResults); got != want {
+		t.Errorf("positionIndicator = %q, want %q", got, want)
+	}
+}
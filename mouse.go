@@ -0,0 +1,180 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	bodyTop           = 4                      // 本体の先頭行（ヘッダー、プロンプト/フッター、区切り線の次）
+	doubleClickWindow = 400 * time.Millisecond // これより短い間隔の2回クリックはダブルクリック
+	wheelStep         = 3                      // ホイール1回で動かす行数
+)
+
+// handleMouse はマウス操作を処理する
+// クリックでカーソル移動、ダブルクリックで選択、ホイールでリストかプレビューをスクロール、
+// ヘッダーのパスをクリックするとそのディレクトリへ移動
+func (m *Model) handleMouse(ev MouseEvent) (bool, []string, error) {
+	if ev.Release || ev.Motion || m.help || m.width < minWidth || m.height < minHeight {
+		return false, nil, nil
+	}
+
+	if ev.Y == 1 {
+		if ev.Button == MouseLeft {
+			if dir, ok := m.breadcrumbAt(ev.X); ok && dir != m.currentDir {
+				return false, nil, m.changeDirectory(dir)
+			}
+		}
+		return false, nil, nil
+	}
+
+	area, row := m.hitTest(ev.X, ev.Y)
+	if area == "parent" {
+		// 親ディレクトリの列はクリックしたディレクトリへ移動
+		if ev.Button == MouseLeft {
+			if dir, ok := m.parentAtRow(row); ok {
+				return false, nil, m.changeDirectory(dir)
+			}
+		}
+		return false, nil, nil
+	}
+
+	switch ev.Button {
+	case MouseWheelUp, MouseWheelDown:
+		delta := wheelStep
+		if ev.Button == MouseWheelUp {
+			delta = -delta
+		}
+		if area == "preview" {
+			m.previewOffset = max(0, min(m.previewOffset+delta, len(m.previewCache)-1))
+			break
+		}
+		// reverse では画面の上が末尾側
+		if m.config.Layout.Reverse {
+			delta = -delta
+		}
+		m.moveCursor(delta)
+
+	case MouseLeft:
+		if area != "list" {
+			break
+		}
+		i := m.entryAtRow(row)
+		if i < 0 {
+			break
+		}
+
+		now := time.Now()
+		double := i == m.lastClickIndex && now.Sub(m.lastClick) < doubleClickWindow
+		m.lastClick, m.lastClickIndex = now, i
+		m.moveCursorTo(i)
+		if double {
+			m.lastClick = time.Time{} // 3回目を再びダブルクリックにしない
+			return m.accept()
+		}
+	}
+	return false, nil, nil
+}
+
+// hitTest は画面座標 (x, y) が本体のどこか（"list" "preview" "parent" ""）と、リストなら表示上の行
+func (m *Model) hitTest(x, y int) (string, int) {
+	row := y - bodyTop
+	if row < 0 || row >= m.bodyHeight() {
+		return "", 0
+	}
+
+	// 3列表示の親ディレクトリの列から右はずらして考える
+	if pw := m.parentWidth(); pw > 0 {
+		if x < pw {
+			return "parent", row
+		}
+		if x == pw {
+			return "", 0
+		}
+		x -= pw
+	}
+
+	pos, size := m.previewPane()
+	listWidth := m.listWidth()
+	listHeight := m.listHeight()
+	switch pos {
+	case "right":
+		if x <= listWidth {
+			return "list", row
+		}
+		if x > listWidth+1 {
+			return "preview", 0
+		}
+	case "left":
+		if x <= size {
+			return "preview", 0
+		}
+		if x > size+1 {
+			return "list", row
+		}
+	case "top":
+		if row < size {
+			return "preview", 0
+		}
+		if row > size {
+			return "list", row - size - 1
+		}
+	case "bottom":
+		if row < listHeight {
+			return "list", row
+		}
+		if row > listHeight {
+			return "preview", 0
+		}
+	default:
+		if x <= listWidth {
+			return "list", row
+		}
+	}
+	return "", 0
+}
+
+// entryAtRow はリストの表示上の行にあるエントリ番号（区切り行や空行なら -1）
+func (m *Model) entryAtRow(row int) int {
+	if m.config.Layout.Reverse {
+		row = m.listHeight() - 1 - row
+	}
+	rows := m.listRows()
+	r := m.offset + row
+	if row < 0 || r >= len(rows) {
+		return -1
+	}
+	return rows[r]
+}
+
+// breadcrumbAt はヘッダーのパスの x 桁目（1始まり）をクリックしたときの移動先
+// "/home/user/src" の "user" 部分なら "/home/user"
+func (m *Model) breadcrumbAt(x int) (string, bool) {
+	dir := m.currentDir
+	col := 0
+	for _, g := range graphemes(dir) {
+		w := clusterWidth(g)
+		if x-1 < col+w {
+			break
+		}
+		col += w
+	}
+
+	// col までの表示幅に相当するバイト位置
+	pos := len(truncatePrefix(dir, col))
+	if pos >= len(dir) {
+		return "", false
+	}
+	if dir[pos] == '/' {
+		if pos == 0 {
+			return "/", true
+		}
+		return dir[:pos], true
+	}
+
+	end := strings.IndexByte(dir[pos:], '/')
+	if end < 0 {
+		return dir, true
+	}
+	return dir[:pos+end], true
+}
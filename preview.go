@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode/utf8"
 )
@@ -59,9 +60,7 @@ func previewFile(path string, maxLines int, size int64) []string {
 		line = strings.ReplaceAll(line, "\t", "    ")
 
 		// 長すぎる行は切り詰め
-		if len(line) > 80 {
-			line = line[:80] + "..."
-		}
+		line = truncateWidth(line, 80)
 
 		lines = append(lines, line)
 		lineNum++
@@ -89,6 +88,11 @@ func previewDirectory(path string, maxLines int) []string {
 		return []string{"Error: " + err.Error()}
 	}
 
+	// ReadDir はバイト順なので自然順に並べ直す
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareNames(entries[i].Name(), entries[j].Name()) < 0
+	})
+
 	var lines []string
 	lines = append(lines, fmt.Sprintf("Directory: %d items", len(entries)))
 	lines = append(lines, "")
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Predicate はメタデータによる絞り込み条件
+type Predicate func(FileEntry) bool
+
+// ParseQuery はクエリから "size:>10M" のような条件を取り出し、残りの検索文字列を返す
+//
+//	size:>10M   サイズ比較（演算子なしは >=、単位 K/M/G/T は1024倍）
+//	mtime:<7d   更新からの経過時間（s/m/h/d/w）。<7d は「7日以内」
+//	mtime:>2024-01-01  日付指定。> はその日以降、< はその日より前、演算子なしと = はその日
+//	ext:go,md   拡張子（カンマ区切りで複数）
+//	type:dir    種類（dir/d, file/f）
+func ParseQuery(query string, now time.Time) (string, []Predicate, error) {
+	var words []string
+	var preds []Predicate
+
+	for _, word := range strings.Fields(query) {
+		key, value, ok := strings.Cut(word, ":")
+		if !ok {
+			words = append(words, word)
+			continue
+		}
+
+		var pred Predicate
+		var err error
+		switch strings.ToLower(key) {
+		case "size":
+			pred, err = parseSizePredicate(value)
+		case "mtime":
+			pred, err = parseMTimePredicate(value, now)
+		case "ext":
+			pred, err = parseExtPredicate(value)
+		case "type":
+			pred, err = parseTypePredicate(value)
+		default:
+			// 未知のキーは通常の文字列として扱う（"a:b" のようなファイル名）
+			words = append(words, word)
+			continue
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %w", key, err)
+		}
+		preds = append(preds, pred)
+	}
+
+	return strings.Join(words, " "), preds, nil
+}
+
+// FilterEntries は全条件を満たすエントリのみ返す
+func FilterEntries(entries []FileEntry, preds []Predicate) []FileEntry {
+	if len(preds) == 0 {
+		return entries
+	}
+
+	var filtered []FileEntry
+	for _, entry := range entries {
+		matched := true
+		for _, pred := range preds {
+			if !pred(entry) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// splitOperator は先頭の比較演算子を切り出す
+func splitOperator(value string) (string, string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "", value
+}
+
+// compareWith は演算子に従って c（比較結果）を判定する
+func compareWith(op string, c int) bool {
+	switch op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return c == 0
+}
+
+func parseSizePredicate(value string) (Predicate, error) {
+	op, num := splitOperator(value)
+	if op == "" {
+		op = ">="
+	}
+
+	size, err := parseSize(num)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(e FileEntry) bool {
+		return !e.IsDir && compareWith(op, compareInt64(e.Size, size))
+	}, nil
+}
+
+// parseSize は "10M" や "512K" をバイト数に変換
+func parseSize(s string) (int64, error) {
+	upper := strings.TrimSuffix(strings.ToUpper(s), "B")
+	multiplier := int64(1)
+	if upper != "" {
+		switch upper[len(upper)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			upper = upper[:len(upper)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(upper, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func parseMTimePredicate(value string, now time.Time) (Predicate, error) {
+	op, arg := splitOperator(value)
+
+	// 日付指定: ModTime と直接比較
+	if t, err := parseDate(arg); err == nil {
+		if op == "" || op == "=" {
+			// 演算子なしと = はその日に更新されたもの
+			end := t.AddDate(0, 0, 1)
+			return func(e FileEntry) bool {
+				return !e.ModTime.Before(t) && e.ModTime.Before(end)
+			}, nil
+		}
+		return func(e FileEntry) bool {
+			return compareWith(op, e.ModTime.Compare(t))
+		}, nil
+	}
+
+	// 経過時間指定: 更新からの経過時間と比較
+	d, err := parseAge(arg)
+	if err != nil {
+		return nil, err
+	}
+	if op == "" {
+		op = "<="
+	}
+	return func(e FileEntry) bool {
+		return compareWith(op, compareInt64(int64(now.Sub(e.ModTime)), int64(d)))
+	}, nil
+}
+
+// parseDate は "2024-01-02" などの日付を解釈（ローカル時刻）
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseAge は "2h" や "7d" を期間に変換
+func parseAge(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	units := map[byte]time.Duration{
+		's': time.Second,
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	unit, ok := units[s[len(s)-1]]
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if !ok || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q (use s/m/h/d/w or YYYY-MM-DD)", s)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+func parseExtPredicate(value string) (Predicate, error) {
+	exts := make(map[string]bool)
+	for _, ext := range strings.Split(value, ",") {
+		ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+		if ext == "" {
+			return nil, fmt.Errorf("empty extension")
+		}
+		exts["."+ext] = true
+	}
+
+	return func(e FileEntry) bool {
+		return !e.IsDir && exts[strings.ToLower(filepath.Ext(e.Name))]
+	}, nil
+}
+
+func parseTypePredicate(value string) (Predicate, error) {
+	switch strings.ToLower(value) {
+	case "dir", "d":
+		return func(e FileEntry) bool { return e.IsDir }, nil
+	case "file", "f":
+		return func(e FileEntry) bool { return !e.IsDir }, nil
+	}
+	return nil, fmt.Errorf("invalid type %q (use dir or file)", value)
+}
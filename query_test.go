@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQueryText(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		query     string
+		wantText  string
+		wantPreds int
+		wantErr   string
+	}{
+		{"handler", "handler", 0, ""},
+		{"api  size:>10M   handler", "api handler", 1, ""},
+		{"ext:go,md type:file mtime:<7d", "", 3, ""},
+		{"a:b.txt", "a:b.txt", 0, ""}, // 未知のキーはそのまま検索文字列
+		{"size:>abc", "", 0, "size"},
+		{"mtime:yesterday", "", 0, "mtime"},
+		{"type:link", "", 0, "type"},
+		{"ext:", "", 0, "ext"},
+	}
+
+	for _, tt := range tests {
+		text, preds, err := ParseQuery(tt.query, now)
+		if tt.wantErr != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("ParseQuery(%q) err = %v, want %q error", tt.query, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseQuery(%q) unexpected error: %v", tt.query, err)
+			continue
+		}
+		if text != tt.wantText || len(preds) != tt.wantPreds {
+			t.Errorf("ParseQuery(%q) = %q, %d predicates; want %q, %d", tt.query, text, len(preds), tt.wantText, tt.wantPreds)
+		}
+	}
+}
+
+func TestParseQueryPredicates(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.Local)
+	entries := map[string]FileEntry{
+		"big":   {Name: "big.iso", Size: 20 << 20, ModTime: now.AddDate(0, 0, -30)},
+		"small": {Name: "main.go", Size: 512, ModTime: now.Add(-2 * time.Hour)},
+		"day":   {Name: "notes.md", Size: 2048, ModTime: time.Date(2024, 1, 2, 15, 30, 0, 0, time.Local)},
+		"dir":   {Name: "src", IsDir: true, ModTime: now},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"size:>10M", []string{"big"}},
+		{"size:1K", []string{"big", "day"}}, // 演算子なしは >=
+		{"size:<=512", []string{"small"}},
+		{"mtime:<7d", []string{"small", "dir"}},
+		{"mtime:>1h", []string{"big", "small", "day"}},
+		{"mtime:2024-01-02", []string{"day"}},
+		{"mtime:=2024-01-02", []string{"day"}}, // = も演算子なしと同じくその日
+		{"mtime:<2024-01-02", []string{"big"}},
+		{"ext:go,.MD", []string{"small", "day"}},
+		{"type:dir", []string{"dir"}},
+		{"type:f size:<1K", []string{"small"}},
+	}
+
+	for _, tt := range tests {
+		_, preds, err := ParseQuery(tt.query, now)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) unexpected error: %v", tt.query, err)
+			continue
+		}
+		for name, entry := range entries {
+			got := len(FilterEntries([]FileEntry{entry}, preds)) == 1
+			want := strings.Contains(" "+strings.Join(tt.want, " ")+" ", " "+name+" ")
+			if got != want {
+				t.Errorf("%q on %s = %v, want %v", tt.query, name, got, want)
+			}
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"512", 512},
+		{"10K", 10 << 10},
+		{"1.5m", 3 << 19},
+		{"2GB", 2 << 30},
+	}
+	for _, tt := range tests {
+		if got, err := parseSize(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "-1", "10X", "M"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) should fail", in)
+		}
+	}
+}
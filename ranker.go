@@ -1,98 +1,583 @@
 package main
 
 import (
+	"fmt"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxResults         = 1000 // クエリで絞り込んだときに一覧に出す最大件数
+	typoFallbackMin    = 3    // 通常マッチがこれ未満なら typo 候補を探す
+	typoMaxSuggestions = 10   // 「もしかして」候補の最大件数
 )
 
 // ScoredEntry はスコア付きファイルエントリ
 type ScoredEntry struct {
-	Entry FileEntry
-	Score int
+	Entry     FileEntry
+	Score     int
+	Spans     [][]int // ハイライト範囲（Path上のバイト位置 [start, end]）
+	Terms     ScoreBreakdown
+	Suggested bool // typo 補正による「もしかして」候補
+}
+
+// ScoreTerm はスコア内訳の1項目
+type ScoreTerm struct {
+	Name  string
+	Value int
+}
+
+// ScoreBreakdown はスコアの内訳（ランキング調整用）
+type ScoreBreakdown []ScoreTerm
+
+// Total は内訳の合計スコア
+func (b ScoreBreakdown) Total() int {
+	total := 0
+	for _, t := range b {
+		total += t.Value
+	}
+	return total
+}
+
+// String は "1500 = prefix 1000 + dir 500" 形式の説明
+func (b ScoreBreakdown) String() string {
+	if len(b) == 0 {
+		return "0"
+	}
+	parts := make([]string, len(b))
+	for i, t := range b {
+		sign := "+"
+		value := t.Value
+		if value < 0 {
+			sign = "-"
+			value = -value
+		}
+		if i == 0 && sign == "+" {
+			parts[i] = fmt.Sprintf("%s %d", t.Name, value)
+		} else {
+			parts[i] = fmt.Sprintf("%s %s %d", sign, t.Name, value)
+		}
+	}
+	return fmt.Sprintf("%d = %s", b.Total(), strings.Join(parts, " "))
+}
+
+func (b *ScoreBreakdown) add(name string, value int) {
+	if value == 0 {
+		return
+	}
+	*b = append(*b, ScoreTerm{Name: name, Value: value})
 }
 
 // RankEntries はクエリに基づいてエントリをランク付け
-func RankEntries(entries []FileEntry, query string) []FileEntry {
+func RankEntries(entries []FileEntry, query string, rc RankingConfig, order SortOrder) []ScoredEntry {
 	if query == "" {
-		return entries[:min(10, len(entries))]
+		return unscored(sortEntries(entries, order))
 	}
 
 	query = strings.ToLower(query)
 	var scored []ScoredEntry
 
 	for _, entry := range entries {
-		score := calculateScore(entry, query)
-		if score > 0 {
+		terms := explainScore(entry, query, rc)
+		if score := terms.Total(); score > 0 {
 			scored = append(scored, ScoredEntry{
 				Entry: entry,
 				Score: score,
+				Terms: terms,
 			})
 		}
 	}
 
-	// スコア降順でソート
+	result := sortScored(scored, rc, order)
+	if len(result) < typoFallbackMin && !strings.Contains(query, "/") {
+		result = append(result, suggestEntries(entries, query, result, rc, order)...)
+	}
+	return result
+}
+
+// suggestEntries はファイル名との編集距離が小さいエントリを「もしかして」候補として返す
+func suggestEntries(entries []FileEntry, query string, exclude []ScoredEntry, rc RankingConfig, order SortOrder) []ScoredEntry {
+	limit := typoMaxSuggestions
+
+	// 短いクエリほど許容する距離を小さく
+	maxDist := 1
+	if len([]rune(query)) > 4 {
+		maxDist = 2
+	}
+
+	seen := make(map[string]bool, len(exclude))
+	for _, e := range exclude {
+		seen[e.Entry.Path] = true
+	}
+
+	var suggested []ScoredEntry
+	queryLen := utf8.RuneCountInString(query)
+	for _, entry := range entries {
+		if seen[entry.Path] {
+			continue
+		}
+
+		// 文字数の差が maxDist を超えるものは距離を計算するまでもない
+		// （小文字化しても文字数は変わらない）
+		stem := strings.TrimSuffix(entry.Name, filepath.Ext(entry.Name))
+		if abs(utf8.RuneCountInString(entry.Name)-queryLen) > maxDist &&
+			abs(utf8.RuneCountInString(stem)-queryLen) > maxDist {
+			continue
+		}
+
+		nameLower := strings.ToLower(entry.Name)
+		dist := editDistance(nameLower, query, maxDist)
+		// 拡張子なしのクエリは拡張子を除いた名前とも比較
+		if !strings.Contains(query, ".") {
+			stem := strings.TrimSuffix(nameLower, filepath.Ext(nameLower))
+			dist = min(dist, editDistance(stem, query, maxDist))
+		}
+		if dist > maxDist {
+			continue
+		}
+
+		var terms ScoreBreakdown
+		terms.add("typo", 100)
+		terms.add("distance", -dist*30)
+		suggested = append(suggested, ScoredEntry{
+			Entry:     entry,
+			Score:     terms.Total(),
+			Terms:     terms,
+			Suggested: true,
+		})
+	}
+
+	sorted := sortScored(suggested, rc, order)
+	return sorted[:min(limit, len(sorted))]
+}
+
+// editDistance は隣接文字の入れ替えも1操作と数える編集距離
+// maxDist を超えると分かった時点で maxDist+1 を返す
+func editDistance(a, b string, maxDist int) int {
+	ar, br := []rune(a), []rune(b)
+	if abs(len(ar)-len(br)) > maxDist {
+		return maxDist + 1
+	}
+
+	prev2 := make([]int, len(br)+1)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > maxDist {
+			return maxDist + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(br)]
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// RankEntriesRegex は正規表現で相対パスを絞り込む
+func RankEntriesRegex(entries []FileEntry, re *regexp.Regexp, rc RankingConfig, order SortOrder) []ScoredEntry {
+	var scored []ScoredEntry
+
+	for _, entry := range entries {
+		spans := re.FindAllStringIndex(entry.Path, -1)
+		if spans == nil {
+			continue
+		}
+
+		// ファイル名部分にマッチしたものを優先、短いパスほど高得点
+		var terms ScoreBreakdown
+		if spans[len(spans)-1][1] > len(entry.Path)-len(entry.Name) {
+			terms.add("name", 1000)
+		}
+		terms.add("length", -len(entry.Path))
+
+		scored = append(scored, ScoredEntry{
+			Entry: entry,
+			Score: terms.Total(),
+			Spans: spans,
+			Terms: terms,
+		})
+	}
+
+	return sortScored(scored, rc, order)
+}
+
+// sortScored はスコア順に並べる
+func sortScored(scored []ScoredEntry, rc RankingConfig, order SortOrder) []ScoredEntry {
+	// スコア降順でソート、同点なら表示中の並び順→設定された順に比較
 	sort.Slice(scored, func(i, j int) bool {
 		if scored[i].Score != scored[j].Score {
 			return scored[i].Score > scored[j].Score
 		}
-		// 同点ならディレクトリ優先、その後名前順
-		if scored[i].Entry.IsDir != scored[j].Entry.IsDir {
-			return scored[i].Entry.IsDir
+		if c := order.compare(scored[i].Entry, scored[j].Entry); c != 0 {
+			return c < 0
 		}
-		return scored[i].Entry.Name < scored[j].Entry.Name
+		return compareTieBreakers(scored[i].Entry, scored[j].Entry, rc.TieBreakers) < 0
 	})
+	return scored
+}
 
-	// 上位10件のみ返す
-	result := make([]FileEntry, 0, min(10, len(scored)))
-	for i := 0; i < min(10, len(scored)); i++ {
-		result = append(result, scored[i].Entry)
+// compareTieBreakers は同点時の並び順で a と b を比較する
+func compareTieBreakers(a, b FileEntry, tieBreakers []string) int {
+	for _, tb := range tieBreakers {
+		c := 0
+		switch tb {
+		case "dirs-first":
+			c = compareBool(a.IsDir, b.IsDir)
+		case "files-first":
+			c = compareBool(!a.IsDir, !b.IsDir)
+		case "name":
+			c = compareNames(a.Name, b.Name)
+		case "path-length":
+			c = len(a.Path) - len(b.Path)
+		case "depth":
+			c = strings.Count(a.Path, string(filepath.Separator)) -
+				strings.Count(b.Path, string(filepath.Separator))
+		}
+		if c != 0 {
+			return c
+		}
 	}
+	return 0
+}
 
+// compareBool は true を先にする比較
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return -1
+	}
+	return 1
+}
+
+// unscored はクエリなしのときの一覧（走査順）
+func unscored(entries []FileEntry) []ScoredEntry {
+	result := make([]ScoredEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = ScoredEntry{Entry: entry}
+	}
 	return result
 }
 
 // calculateScore はマッチスコアを計算
-func calculateScore(entry FileEntry, query string) int {
+func calculateScore(entry FileEntry, query string, rc RankingConfig) int {
+	return explainScore(entry, query, rc).Total()
+}
+
+// explainScore はマッチスコアを内訳付きで計算
+func explainScore(entry FileEntry, query string, rc RankingConfig) ScoreBreakdown {
 	nameLower := strings.ToLower(entry.Name)
-	score := 0
+	var terms ScoreBreakdown
+
+	// "/" を含むクエリはパスのセグメント単位でマッチ
+	if strings.Contains(query, "/") {
+		return explainSegments(entry, query)
+	}
 
 	// ディレクトリ名完全マッチ: 最優先
 	if entry.IsDir && nameLower == query {
-		return 10000
+		terms.add("exact-dir", rc.ExactDir)
+		return terms
 	}
 
 	// ベースファイル名の前方一致: 高得点
 	if strings.HasPrefix(nameLower, query) {
-		score += 1000
+		terms.add("prefix", rc.Prefix)
 		if entry.IsDir {
-			score += 500 // ディレクトリならさらにボーナス
+			terms.add("dir", rc.PrefixDirBonus) // ディレクトリならさらにボーナス
 		}
-		return score
+		return terms
 	}
 
 	// ベースファイル名の部分一致
 	if idx := strings.Index(nameLower, query); idx >= 0 {
-		score += 500 - idx*10 // 前方に近いほど高得点
+		terms.add("substring", rc.Substring)
+		terms.add("position", -idx*rc.SubstringPosition) // 前方に近いほど高得点
+		// idx は nameLower 上のバイト位置（小文字化でバイト数が変わる文字があるので文字数に直す）
+		// 小文字化は1文字ずつの変換なので文字数は entry.Name と同じ
+		if isWordStart(entry.Name, utf8.RuneCountInString(nameLower[:idx])) {
+			terms.add("boundary", rc.BoundaryBonus)
+		}
 		if entry.IsDir {
-			score += 200
+			terms.add("dir", rc.SubstringDirBonus)
+		}
+		return terms
+	}
+
+	// ベースファイル名の曖昧一致（部分列）: 単語の先頭での一致を優先
+	// 大半のエントリは部分列ですらないので、先に安い判定で落としてから DP をする
+	if isSubsequence(nameLower, query) {
+		if fm, ok := fuzzyMatch(entry.Name, query, rc); ok {
+			terms.add("fuzzy", rc.Fuzzy)
+			terms.add("boundary", fm.boundary*rc.BoundaryBonus)
+			terms.add("camel", fm.camel*rc.CamelCaseBonus)
+			terms.add("consecutive", fm.consecutive*rc.ConsecutiveBonus)
+			return terms
+		}
+	}
+
+	// 親ディレクトリ名マッチ（下層から。Split せずに後ろから1つずつ見る）
+	for dir, depth := entry.DirPath, 0; dir != "."; depth++ {
+		i := strings.LastIndexByte(dir, filepath.Separator)
+		if strings.Contains(strings.ToLower(dir[i+1:]), query) {
+			terms.add("parent-dir", rc.ParentDir)
+			terms.add("depth", -depth*rc.ParentDepth) // 下層ほど高得点
+			break
+		}
+		if i < 0 {
+			break
+		}
+		dir = dir[:i]
+	}
+
+	return terms
+}
+
+// fuzzyResult は曖昧一致で得たボーナスの回数
+type fuzzyResult struct {
+	boundary    int // 区切り文字直後（または先頭）での一致数
+	camel       int // camelCase の山での一致数
+	consecutive int // 直前の文字に続けて一致した数
+}
+
+func (f fuzzyResult) value(rc RankingConfig) int {
+	return f.boundary*rc.BoundaryBonus + f.camel*rc.CamelCaseBonus + f.consecutive*rc.ConsecutiveBonus
+}
+
+// fuzzyStep は曖昧一致の DP の1マス（ok が false なら一致できない位置）
+type fuzzyStep struct {
+	fuzzyResult
+	ok bool
+}
+
+// fuzzyMatch は query を name の部分列として、ボーナスが最大になる位置に当てはめる
+func fuzzyMatch(name, query string, rc RankingConfig) (fuzzyResult, bool) {
+	nameRunes := []rune(name)
+	queryRunes := []rune(query)
+	if len(queryRunes) == 0 || len(queryRunes) > len(nameRunes) {
+		return fuzzyResult{}, false
+	}
+
+	// prev[j]: 直前のクエリ文字が j 番目に一致したときの最良結果（2行を使い回す）
+	prev := make([]fuzzyStep, len(nameRunes))
+	cur := make([]fuzzyStep, len(nameRunes))
+	for i, qr := range queryRunes {
+		// best は prev[0..j-2] のうち最良のもの（連続ボーナスが付かない直前の一致）
+		var best fuzzyStep
+		for j, nr := range nameRunes {
+			cur[j] = fuzzyStep{}
+			if j >= 2 && prev[j-2].ok && (!best.ok || prev[j-2].value(rc) > best.value(rc)) {
+				best = prev[j-2]
+			}
+			if unicode.ToLower(nr) != qr {
+				continue
+			}
+
+			var bonus fuzzyResult
+			switch {
+			case isCamelHump(nameRunes, j):
+				bonus.camel = 1
+			case isBoundary(nameRunes, j):
+				bonus.boundary = 1
+			}
+
+			if i == 0 {
+				cur[j] = fuzzyStep{bonus, true}
+				continue
+			}
+
+			// 離れた位置からの一致と、直前の文字からの連続した一致のよい方
+			from := best
+			if j >= 1 && prev[j-1].ok {
+				next := prev[j-1]
+				next.consecutive++
+				if !from.ok || next.value(rc) > from.value(rc) {
+					from = next
+				}
+			}
+			if !from.ok {
+				continue
+			}
+			cur[j] = fuzzyStep{fuzzyResult{
+				boundary:    from.boundary + bonus.boundary,
+				camel:       from.camel + bonus.camel,
+				consecutive: from.consecutive,
+			}, true}
+		}
+		prev, cur = cur, prev
+	}
+
+	var best fuzzyStep
+	for _, cand := range prev {
+		if cand.ok && (!best.ok || cand.value(rc) > best.value(rc)) {
+			best = cand
+		}
+	}
+	return best.fuzzyResult, best.ok
+}
+
+// isWordStart は i 文字目が単語の先頭（区切り直後か camelCase の山）か
+func isWordStart(name string, i int) bool {
+	runes := []rune(name)
+	return isBoundary(runes, i) || isCamelHump(runes, i)
+}
+
+// isBoundary は i 文字目が先頭または区切り文字の直後か
+func isBoundary(runes []rune, i int) bool {
+	if i == 0 {
+		return true
+	}
+	switch runes[i-1] {
+	case '_', '-', '.', '/', ' ':
+		return true
+	}
+	return false
+}
+
+// isCamelHump は i 文字目が小文字に続く大文字か
+func isCamelHump(runes []rune, i int) bool {
+	return i > 0 && unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1])
+}
+
+const (
+	segmentGapPenalty = 30  // 飛ばしたセグメント1つあたりの減点
+	maxSegmentGap     = 250 // 減点の上限（深いパスでも一致すればスコアが正になるように）
+)
+
+// segmentMatch はセグメント整列の途中結果
+type segmentMatch struct {
+	match int // セグメント一致点の合計
+	gap   int // 飛ばしたセグメントの減点
+	prev  int // 直前のクエリセグメントが一致したパス位置
+}
+
+// explainSegments は "api/handler" のようなクエリをパスのセグメントに順番に当てはめる
+// 各セグメントは前方一致か曖昧一致（部分列）で、間のセグメントが少ないほど高得点
+func explainSegments(entry FileEntry, query string) ScoreBreakdown {
+	var querySegs []string
+	for _, seg := range strings.Split(query, "/") {
+		if seg != "" {
+			querySegs = append(querySegs, seg)
 		}
-		return score
+	}
+	if len(querySegs) == 0 {
+		return nil
 	}
 
-	// 親ディレクトリ名マッチ（下層から）
-	if entry.DirPath != "." {
-		dirs := strings.Split(entry.DirPath, string(filepath.Separator))
-		for i := len(dirs) - 1; i >= 0; i-- {
-			dirLower := strings.ToLower(dirs[i])
-			if strings.Contains(dirLower, query) {
-				score += 100 - (len(dirs)-1-i)*20 // 下層ほど高得点
-				break
+	pathSegs := strings.Split(strings.ToLower(entry.Path), string(filepath.Separator))
+	if len(querySegs) > len(pathSegs) {
+		return nil
+	}
+
+	// dp[i][j]: クエリ i 番目がパス j 番目に一致したときの最良値
+	dp := make([][]*segmentMatch, len(querySegs))
+	for i, qs := range querySegs {
+		dp[i] = make([]*segmentMatch, len(pathSegs))
+		for j, ps := range pathSegs {
+			point := segmentScore(ps, qs)
+			if point == 0 {
+				continue
+			}
+			if i == 0 {
+				dp[i][j] = &segmentMatch{match: point, prev: -1}
+				continue
+			}
+			for k := i - 1; k < j; k++ {
+				prev := dp[i-1][k]
+				if prev == nil {
+					continue
+				}
+				cand := segmentMatch{
+					match: prev.match + point,
+					gap:   prev.gap - (j-k-1)*segmentGapPenalty,
+					prev:  k,
+				}
+				cur := dp[i][j]
+				if cur == nil || cand.match+cand.gap > cur.match+cur.gap {
+					dp[i][j] = &cand
+				}
 			}
 		}
 	}
 
-	return score
+	// 最後のクエリセグメントの最良位置を選ぶ
+	last := len(querySegs) - 1
+	best, bestJ := (*segmentMatch)(nil), -1
+	for j, cand := range dp[last] {
+		if cand == nil {
+			continue
+		}
+		if best == nil || cand.match+cand.gap > best.match+best.gap {
+			best, bestJ = cand, j
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	var terms ScoreBreakdown
+	terms.add("segments", 300+best.match)
+	terms.add("gap", max(best.gap, -maxSegmentGap))
+	if bestJ == len(pathSegs)-1 {
+		terms.add("name", 200) // 最後のセグメントがファイル名に一致
+	}
+	return terms
+}
+
+// segmentScore はパスの1セグメントとクエリの1セグメントの一致点（0は不一致）
+func segmentScore(seg, query string) int {
+	switch {
+	case seg == query:
+		return 150
+	case strings.HasPrefix(seg, query):
+		return 100
+	case isSubsequence(seg, query):
+		return 40
+	}
+	return 0
+}
+
+// isSubsequence は query の文字が順番通りに s に含まれるか
+// 全エントリに対して呼ぶので割り当てをしない
+func isSubsequence(s, query string) bool {
+	for _, r := range s {
+		if query == "" {
+			return true
+		}
+		qr, size := utf8.DecodeRuneInString(query)
+		if r == qr {
+			query = query[size:]
+		}
+	}
+	return query == ""
 }
 
 func min(a, b int) int {
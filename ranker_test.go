@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEntry は相対パスからエントリを作る（末尾が "/" ならディレクトリ）
+func testEntry(path string) FileEntry {
+	isDir := path[len(path)-1] == '/'
+	path = filepath.Clean(path)
+	return FileEntry{
+		Path:    path,
+		Name:    filepath.Base(path),
+		IsDir:   isDir,
+		DirPath: filepath.Dir(path),
+	}
+}
+
+func testEntries(paths ...string) []FileEntry {
+	entries := make([]FileEntry, len(paths))
+	for i, path := range paths {
+		entries[i] = testEntry(path)
+	}
+	return entries
+}
+
+// rankOf は結果の中で path が何番目か（なければ -1）
+func rankOf(result []ScoredEntry, path string) int {
+	for i, scored := range result {
+		if scored.Entry.Path == path {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRankEntriesOrdering(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		entries []string
+		better  string
+		worse   string
+	}{
+		{"camelCase の山", "hf", []string{"shelf.go", "HandlerFactory.go"}, "HandlerFactory.go", "shelf.go"},
+		{"区切り文字の直後", "hf", []string{"thief.go", "handler_factory.go"}, "handler_factory.go", "thief.go"},
+		{"snake_case の単語先頭", "uh", []string{"mouthful.go", "user_handler.go"}, "user_handler.go", "mouthful.go"},
+		{"単語先頭の部分一致", "test", []string{"latest.go", "my_test.go"}, "my_test.go", "latest.go"},
+		{"前方一致は部分一致より上", "main", []string{"domain.go", "main.go"}, "main.go", "domain.go"},
+		{"ディレクトリ名の完全一致が最優先", "api", []string{"api.go", "api/"}, "api", "api.go"},
+		{"ファイル名一致は親ディレクトリ一致より上", "model", []string{"model/view.go", "model.go"}, "model.go", "model/view.go"},
+		{"セグメントの間が詰まっているほど上", "api/handler", []string{"services/api/http/handler.go", "api/handler.go"}, "api/handler.go", "services/api/http/handler.go"},
+		{"最後のセグメントがファイル名", "api/handler", []string{"api/handler/doc.txt", "api/handler.go"}, "api/handler.go", "api/handler/doc.txt"},
+		{"親ディレクトリ一致は近い階層ほど上", "src", []string{"src/a/b/x.go", "a/src/y.go"}, "a/src/y.go", "src/a/b/x.go"},
+		{"小文字化でバイト数が変わる名前", "x", []string{"ȺȺȺȺx.go", "ȺȺȺȺ_x.go"}, "ȺȺȺȺ_x.go", "ȺȺȺȺx.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := RankEntries(testEntries(tt.entries...), tt.query, DefaultRankingConfig(), SortOrder{})
+			better, worse := rankOf(result, tt.better), rankOf(result, tt.worse)
+			if better < 0 || worse < 0 {
+				t.Fatalf("%q: %s (%d) or %s (%d) not matched", tt.query, tt.better, better, tt.worse, worse)
+			}
+			if better > worse {
+				t.Errorf("%q: %s (%v) should rank above %s (%v)",
+					tt.query, tt.better, result[better].Terms, tt.worse, result[worse].Terms)
+			}
+		})
+	}
+}
+
+func TestRankEntriesSegmentMatchInDeepPath(t *testing.T) {
+	// 間のセグメントが多くても、すべてのセグメントが一致すれば候補に残る
+	path := "a/" + strings.Repeat("x/", 30) + "zeta.go"
+	result := RankEntries(testEntries(path), "a/zeta", DefaultRankingConfig(), SortOrder{})
+	if rankOf(result, path) < 0 {
+		t.Fatalf("%s not matched: %v", path, explainScore(testEntry(path), "a/zeta", DefaultRankingConfig()))
+	}
+}
+
+func TestRankEntriesBonusesAreConfigurable(t *testing.T) {
+	// ボーナスを0にすると、単語先頭での一致も途中での一致と同点になる
+	rc := DefaultRankingConfig()
+	rc.BoundaryBonus, rc.CamelCaseBonus = 0, 0
+	result := RankEntries(testEntries("shelf.go", "HandlerFactory.go"), "hf", rc, SortOrder{})
+	if len(result) != 2 || result[0].Score != result[1].Score {
+		t.Errorf("expected equal scores without bonuses, got %v", result)
+	}
+}
+
+func TestRankEntriesTypoSuggestion(t *testing.T) {
+	result := RankEntries(testEntries("main.go", "render.go"), "mian", DefaultRankingConfig(), SortOrder{})
+	if len(result) != 1 || result[0].Entry.Path != "main.go" || !result[0].Suggested {
+		t.Errorf("expected main.go as a suggestion, got %v", result)
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  fuzzyResult
+		ok    bool
+	}{
+		{"HandlerFactory.go", "hf", fuzzyResult{boundary: 1, camel: 1}, true},
+		{"user_handler.go", "uh", fuzzyResult{boundary: 2}, true},
+		{"shelf.go", "hf", fuzzyResult{}, true},
+		{"abc", "ab", fuzzyResult{boundary: 1, consecutive: 1}, true},
+		{"file-name.txt", "fnt", fuzzyResult{boundary: 3}, true},
+		{"ab", "abc", fuzzyResult{}, false},
+		{"abc", "ca", fuzzyResult{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := fuzzyMatch(tt.name, tt.query, DefaultRankingConfig())
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %+v, %v; want %+v, %v", tt.name, tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsWordStart(t *testing.T) {
+	tests := []struct {
+		name string
+		i    int
+		want bool
+	}{
+		{"handler", 0, true},
+		{"user_handler", 5, true},
+		{"my-file", 3, true},
+		{"a.b", 2, true},
+		{"HandlerFactory", 7, true},
+		{"HTTPServer", 1, false},
+		{"handler", 3, false},
+	}
+
+	for _, tt := range tests {
+		if got := isWordStart(tt.name, tt.i); got != tt.want {
+			t.Errorf("isWordStart(%q, %d) = %v, want %v", tt.name, tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		maxDist int
+		want    int
+	}{
+		{"main", "main", 2, 0},
+		{"main", "mian", 2, 1}, // 入れ替えは1操作
+		{"main", "man", 2, 1},
+		{"render", "rnder", 2, 1},
+		{"abc", "xyz", 1, 2}, // 上限を超えたら maxDist+1
+		{"a", "abcd", 2, 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.maxDist); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.maxDist, got, tt.want)
+		}
+	}
+}
+
+// benchEntries は max_files（10万件）相当のエントリ
+func benchEntries() []FileEntry {
+	words := []string{"handler", "Factory", "user", "render", "config", "model", "view", "index", "util", "Server"}
+	exts := []string{".go", ".ts", ".md", ".json", ".txt"}
+	var paths []string
+	for i := 0; len(paths) < 100000; i++ {
+		dir := fmt.Sprintf("pkg%d/%s/%s%d", i%37, words[i%10], words[(i/10)%10], i%13)
+		name := fmt.Sprintf("%s_%s%d%s", words[(i/3)%10], words[(i/7)%10], i, exts[i%5])
+		paths = append(paths, dir+"/"+name)
+	}
+	return testEntries(paths...)
+}
+
+func BenchmarkRankEntries(b *testing.B) {
+	entries := benchEntries()
+	for _, query := range []string{"sfn", "sofina", "zzqx", "hf", "handler"} {
+		b.Run(query, func(b *testing.B) {
+			for b.Loop() {
+				RankEntries(entries, query, DefaultRankingConfig(), SortOrder{})
+			}
+		})
+	}
+}
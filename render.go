@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// cell は画面の1マス
+type cell struct {
+	ch    string // 表示する文字（結合文字を含む）。全角の右半分は ""
+	style string // SGR パラメータ（"1;34" など）。"" はデフォルト
+}
+
+var blankCell = cell{ch: " "}
+
+// Renderer は前回のフレームを覚えておき、変わったマスだけを書き換える
+// 毎回 \033[2J で全消去するとSSHやtmux越しでちらつくため
+type Renderer struct {
+	out       io.Writer
+	width     int
+	height    int
+	prev      [][]cell // 前回描画した内容
+	sync      bool     // 同期出力モード（DEC 2026）を使う
+	inline    bool     // 画面下部の height 行だけを使う（--height）
+	cursorRow int      // インラインモードで端末のカーソルがある行（描画領域内、0始まり）
+}
+
+// NewRenderer は out に描画するレンダラーを作る
+// inline のときは現在行の下に height 行を確保し、そこだけに描画する
+func NewRenderer(out io.Writer, width, height int, sync, inline bool) *Renderer {
+	r := &Renderer{out: out, sync: sync, inline: inline}
+	r.Resize(width, height)
+	return r
+}
+
+// Resize は画面サイズを変え、次回は全体を描き直す
+func (r *Renderer) Resize(width, height int) {
+	if r.inline && height > r.height {
+		// 足りない行数だけ改行して領域を広げる（画面下端ならスクロールする）
+		var b strings.Builder
+		if r.height > 0 {
+			r.moveTo(&b, r.height-1, 0)
+		}
+		b.WriteString(strings.Repeat("\n", height-r.height))
+		io.WriteString(r.out, b.String())
+		r.cursorRow = height - 1
+	}
+	r.width = width
+	r.height = height
+	r.Invalidate()
+}
+
+// Invalidate は画面を消去し、次回の描画で全体を描き直す
+// （レンダラーを通さずに画面へ書いたときに呼ぶ）
+func (r *Renderer) Invalidate() {
+	if r.inline {
+		r.Clear()
+	} else {
+		fmt.Fprint(r.out, "\033[0m\033[2J")
+	}
+	r.prev = blankFrame(r.width, r.height)
+}
+
+// Clear は描画領域を消去し、カーソルを領域の先頭に置く（終了時に呼ぶ）
+func (r *Renderer) Clear() {
+	var b strings.Builder
+	if r.inline {
+		r.moveTo(&b, 0, 0)
+		b.WriteString("\033[0m\033[J")
+	} else {
+		b.WriteString("\033[0m\033[2J\033[H")
+	}
+	io.WriteString(r.out, b.String())
+}
+
+// Notice は描画領域の最終行にメッセージを出す（次の描画で上書きされる）
+func (r *Renderer) Notice(msg string) {
+	if r.height == 0 {
+		return
+	}
+	var b strings.Builder
+	y := r.height - 1
+	r.moveTo(&b, y, 0)
+	b.WriteString("\033[0m\033[K" + truncateANSI(msg, r.width) + "\033[0m")
+	io.WriteString(r.out, b.String())
+
+	// 次回はこの行を必ず描き直す
+	for x := range r.prev[y] {
+		r.prev[y][x] = cell{ch: "\x00"}
+	}
+}
+
+// moveTo はカーソルを描画領域内の (y, x) に移動する（0始まり）
+// インラインモードでは領域の画面上の位置が分からないので相対移動する
+func (r *Renderer) moveTo(b *strings.Builder, y, x int) {
+	if !r.inline {
+		fmt.Fprintf(b, "\033[%d;%dH", y+1, x+1)
+		return
+	}
+
+	switch dy := y - r.cursorRow; {
+	case dy < 0:
+		fmt.Fprintf(b, "\033[%dA", -dy)
+	case dy > 0:
+		fmt.Fprintf(b, "\033[%dB", dy)
+	}
+	b.WriteString("\r")
+	if x > 0 {
+		fmt.Fprintf(b, "\033[%dC", x)
+	}
+	r.cursorRow = y
+}
+
+// Render は frame（View の出力）を描画し、カーソルを (row, col) に置く（1始まり）
+func (r *Renderer) Render(frame string, row, col int) {
+	next := parseFrame(frame, r.width, r.height)
+
+	var b strings.Builder
+	if r.sync {
+		b.WriteString("\033[?2026h")
+	}
+
+	for y := range next {
+		r.diffRow(&b, y, r.prev[y], next[y])
+	}
+	r.moveTo(&b, row-1, col-1)
+
+	if r.sync {
+		b.WriteString("\033[?2026l")
+	}
+
+	io.WriteString(r.out, b.String())
+	r.prev = next
+}
+
+// diffRow は1行分の差分を出力する
+func (r *Renderer) diffRow(b *strings.Builder, y int, old, cur []cell) {
+	// 変化した範囲 [first, last] を探す
+	first, last := -1, -1
+	for x := range cur {
+		if cur[x] != old[x] {
+			if first < 0 {
+				first = x
+			}
+			last = x
+		}
+	}
+	if first < 0 {
+		return
+	}
+
+	// 全角文字の右半分から書き始めない
+	for first > 0 && cur[first].ch == "" {
+		first--
+	}
+
+	// 末尾が空白だけなら行末消去でまとめる
+	end := len(cur)
+	for end > first && cur[end-1] == blankCell {
+		end--
+	}
+	clearToEnd := end <= last
+
+	r.moveTo(b, y, first)
+	style := ""
+	b.WriteString("\033[0m")
+	for x := first; x < min(end, last+1); x++ {
+		c := cur[x]
+		if c.ch == "" {
+			continue
+		}
+		if c.style != style {
+			if style != "" {
+				b.WriteString("\033[0m")
+			}
+			if c.style != "" {
+				b.WriteString("\033[" + c.style + "m")
+			}
+			style = c.style
+		}
+		b.WriteString(c.ch)
+	}
+	b.WriteString("\033[0m")
+	if clearToEnd {
+		b.WriteString("\033[K")
+	}
+}
+
+// blankFrame は空白だけのフレーム
+func blankFrame(width, height int) [][]cell {
+	frame := make([][]cell, height)
+	for y := range frame {
+		frame[y] = make([]cell, width)
+		for x := range frame[y] {
+			frame[y][x] = blankCell
+		}
+	}
+	return frame
+}
+
+// parseFrame はエスケープシーケンス付きの文字列をマスに分解する
+// SGR（色・装飾）だけを解釈し、\033[K などその他の制御は無視する
+func parseFrame(frame string, width, height int) [][]cell {
+	cells := blankFrame(width, height)
+	lines := strings.Split(frame, "\n")
+
+	for y := 0; y < min(len(lines), height); y++ {
+		row := cells[y]
+		x := 0
+		style := ""
+
+	segments:
+		for _, seg := range splitANSI(lines[y]) {
+			if seg.escape {
+				if strings.HasPrefix(seg.text, "\033[") && strings.HasSuffix(seg.text, "m") {
+					style = applySGR(style, seg.text[2:len(seg.text)-1])
+				}
+				continue
+			}
+
+			for _, g := range graphemes(strings.ReplaceAll(seg.text, "\r", "")) {
+				w := clusterWidth(g)
+				if w == 0 {
+					// 単独の結合文字などは直前のマスにくっつける
+					if x > 0 {
+						prev := x - 1
+						for prev > 0 && row[prev].ch == "" {
+							prev--
+						}
+						row[prev].ch += g
+					}
+					continue
+				}
+				if x+w > width {
+					break segments
+				}
+
+				row[x] = cell{ch: g, style: style}
+				if w == 2 {
+					row[x+1] = cell{ch: "", style: style}
+				}
+				x += w
+			}
+		}
+	}
+
+	return cells
+}
+
+// applySGR は現在のスタイルに SGR パラメータを重ねる（0 でリセット）
+func applySGR(style, params string) string {
+	if params == "" || params == "0" {
+		return ""
+	}
+	if strings.HasPrefix(params, "0;") {
+		return params[2:]
+	}
+	if style == "" {
+		return params
+	}
+	return style + ";" + params
+}
+
+// supportsSyncOutput は同期出力モード（DEC 2026）に対応した端末か
+// 対応していない端末も普通は無視するが、念のため既知の端末だけで使う
+func supportsSyncOutput(mode string) bool {
+	switch mode {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "WezTerm", "iTerm.app", "ghostty", "vscode", "WarpTerminal":
+		return true
+	}
+
+	term := os.Getenv("TERM")
+	for _, name := range []string{"kitty", "foot", "alacritty", "contour", "ghostty", "wezterm"} {
+		if strings.Contains(term, name) {
+			return true
+		}
+	}
+	return false
+}
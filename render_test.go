@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRendererWritesOnlyChangedCells(t *testing.T) {
+	var out strings.Builder
+	r := NewRenderer(&out, 10, 3, false, false)
+	r.Render("header\nlist\nfooter", 1, 1)
+
+	out.Reset()
+	r.Render("header\nlost\nfooter", 1, 1)
+	if got := out.String(); strings.Contains(got, "header") || strings.Contains(got, "footer") || !strings.Contains(got, "o") {
+		t.Errorf("second render wrote %q, want only the changed cell", got)
+	}
+}
+
+func TestRendererNoticeIsRedrawnByNextRender(t *testing.T) {
+	var out strings.Builder
+	r := NewRenderer(&out, 20, 3, false, false)
+	r.Render("header\nlist\nfooter", 1, 1)
+
+	out.Reset()
+	r.Notice("Error: boom")
+	if got := out.String(); !strings.Contains(got, "Error: boom") || !strings.Contains(got, "\033[3;1H") {
+		t.Fatalf("notice wrote %q, want the message on the last row", got)
+	}
+
+	// 同じフレームでも、メッセージを出した行は描き直す
+	out.Reset()
+	r.Render("header\nlist\nfooter", 1, 1)
+	if got := out.String(); !strings.Contains(got, "footer") || strings.Contains(got, "header") {
+		t.Errorf("render after notice wrote %q, want only the last row", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // FileEntry はファイル/ディレクトリ情報
@@ -11,7 +12,10 @@ type FileEntry struct {
 	Path    string
 	Name    string
 	IsDir   bool
-	DirPath string // 親ディレクトリパス
+	DirPath string      // 親ディレクトリパス
+	Size    int64       // ファイルサイズ（バイト）
+	ModTime time.Time   // 最終更新日時
+	Mode    os.FileMode // 種類とパーミッション（シンボリックリンクはリンク自体）
 }
 
 // ScanFiles は指定ディレクトリ配下を走査する
@@ -65,13 +69,23 @@ func ScanFiles(rootDir string, config Config) ([]FileEntry, error) {
 
 		dirPath := filepath.Dir(relPath)
 
-		entries = append(entries, FileEntry{
+		entry := FileEntry{
 			Path:    relPath,
 			Name:    d.Name(),
 			IsDir:   d.IsDir(),
 			DirPath: dirPath,
-		})
+		}
+		if info, err := d.Info(); err == nil {
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime()
+			entry.Mode = info.Mode()
+		}
+		entries = append(entries, entry)
 
+		// 上限の深さのディレクトリは中身を読まない（子はすべて深度チェックで捨てる）
+		if d.IsDir() && depth == config.MaxDepth {
+			return filepath.SkipDir
+		}
 		return nil
 	})
 
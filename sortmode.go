@@ -0,0 +1,99 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SortMode は一覧の並び順の種類
+type SortMode int
+
+const (
+	SortScan  SortMode = iota // 走査順（デフォルト）
+	SortName                  // ファイル名
+	SortMTime                 // 更新日時
+	SortSize                  // サイズ
+	SortPath                  // 相対パス
+	SortExt                   // 拡張子
+)
+
+var sortModeNames = []string{"scan", "name", "mtime", "size", "path", "ext"}
+
+func (s SortMode) String() string {
+	return sortModeNames[s]
+}
+
+// SortOrder は並び順と昇順/降順
+type SortOrder struct {
+	Mode SortMode
+	Desc bool
+}
+
+// Next は次の並び順（順番に切り替え）
+func (o SortOrder) Next() SortOrder {
+	o.Mode = (o.Mode + 1) % SortMode(len(sortModeNames))
+	return o
+}
+
+// String はステータス行用の表示 "name↑"
+func (o SortOrder) String() string {
+	if o.Desc {
+		return o.Mode.String() + "↓"
+	}
+	return o.Mode.String() + "↑"
+}
+
+// compare は並び順で a と b を比較する（走査順なら常に0）
+func (o SortOrder) compare(a, b FileEntry) int {
+	c := 0
+	switch o.Mode {
+	case SortName:
+		c = compareNames(a.Name, b.Name)
+	case SortMTime:
+		c = a.ModTime.Compare(b.ModTime)
+	case SortSize:
+		c = compareInt64(a.Size, b.Size)
+	case SortPath:
+		c = compareNames(a.Path, b.Path)
+	case SortExt:
+		c = strings.Compare(strings.ToLower(filepath.Ext(a.Name)), strings.ToLower(filepath.Ext(b.Name)))
+		if c == 0 {
+			c = compareNames(a.Name, b.Name)
+		}
+	}
+	if o.Desc {
+		return -c
+	}
+	return c
+}
+
+// sortEntries はクエリなしのときの一覧を並べ替える
+func sortEntries(entries []FileEntry, order SortOrder) []FileEntry {
+	sorted := make([]FileEntry, len(entries))
+	copy(sorted, entries)
+
+	if order.Mode == SortScan {
+		if order.Desc {
+			for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+				sorted[i], sorted[j] = sorted[j], sorted[i]
+			}
+		}
+		return sorted
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order.compare(sorted[i], sorted[j]) < 0
+	})
+	return sorted
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Theme は画面の各部分の配色
+// 値は "bold blue" "underline #ff8800" "bg:236 white" のような空白区切りの指定
+// （色名、0〜255 のパレット番号、#rrggbb、bold/dim/italic/underline/reverse）
+type Theme struct {
+	Cursor     string `json:"cursor"`     // カーソル記号 ">"
+	Mark       string `json:"mark"`       // マーク記号 "*" とマーク数
+	Directory  string `json:"directory"`  // ディレクトリ名
+	File       string `json:"file"`       // ファイル名
+	Match      string `json:"match"`      // クエリに一致した部分
+	Header     string `json:"header"`     // ヘッダーのディレクトリ名
+	Info       string `json:"info"`       // ファイル数、フッター、スコア内訳
+	Prompt     string `json:"prompt"`     // 正規表現モードのプロンプト
+	Error      string `json:"error"`      // エラー表示
+	Border     string `json:"border"`     // 区切り線
+	Scrollbar  string `json:"scrollbar"`  // スクロールバーのつまみ
+	Preview    string `json:"preview"`    // プレビュー本文
+	Suggestion string `json:"suggestion"` // 「もしかして」の区切り行
+}
+
+// fields は各項目へのポインタ（名前は設定ファイルのキー）
+func (t *Theme) fields() []struct {
+	name  string
+	value *string
+} {
+	return []struct {
+		name  string
+		value *string
+	}{
+		{"cursor", &t.Cursor},
+		{"mark", &t.Mark},
+		{"directory", &t.Directory},
+		{"file", &t.File},
+		{"match", &t.Match},
+		{"header", &t.Header},
+		{"info", &t.Info},
+		{"prompt", &t.Prompt},
+		{"error", &t.Error},
+		{"border", &t.Border},
+		{"scrollbar", &t.Scrollbar},
+		{"preview", &t.Preview},
+		{"suggestion", &t.Suggestion},
+	}
+}
+
+// builtinThemes は組み込みテーマ
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Cursor:     "bold yellow",
+		Mark:       "bold magenta",
+		Directory:  "bold blue",
+		Match:      "bold underline green",
+		Header:     "bold cyan",
+		Info:       "dim",
+		Prompt:     "bold magenta",
+		Error:      "bold red",
+		Scrollbar:  "cyan",
+		Suggestion: "dim yellow",
+	},
+	"light": {
+		Cursor:     "bold 130",
+		Mark:       "bold 127",
+		Directory:  "bold 25",
+		Match:      "bold underline 28",
+		Header:     "bold 30",
+		Info:       "242",
+		Prompt:     "bold 127",
+		Error:      "bold 160",
+		Border:     "245",
+		Scrollbar:  "30",
+		Suggestion: "130",
+	},
+	"high-contrast": {
+		Cursor:     "bold bright-yellow",
+		Mark:       "bold bright-magenta",
+		Directory:  "bold bright-cyan",
+		File:       "bright-white",
+		Match:      "bold reverse",
+		Header:     "bold bright-white",
+		Info:       "white",
+		Prompt:     "bold bright-magenta",
+		Error:      "bold bright-white bg:red",
+		Border:     "bright-white",
+		Scrollbar:  "bright-yellow",
+		Preview:    "bright-white",
+		Suggestion: "bold bright-yellow",
+	},
+}
+
+// Validate は配色の指定を解釈できるかチェック
+func (t Theme) Validate() error {
+	for _, f := range t.fields() {
+		if _, err := parseStyle(*f.value, colorTrue); err != nil {
+			return fmt.Errorf("colors.%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// ResolveTheme は組み込みテーマ name に overrides を重ね、SGR パラメータに変換する
+// noColor のときは色を使わず bold などの装飾だけ残す
+func ResolveTheme(name string, overrides Theme, depth colorDepth, noColor bool) Theme {
+	theme, ok := builtinThemes[name]
+	if !ok {
+		theme = builtinThemes["dark"]
+	}
+
+	src := overrides.fields()
+	for i, f := range theme.fields() {
+		if *src[i].value != "" {
+			*f.value = *src[i].value
+		}
+		if noColor {
+			*f.value = stripColors(*f.value)
+		}
+		*f.value, _ = parseStyle(*f.value, depth)
+	}
+	return theme
+}
+
+// paint は text を SGR パラメータ style で装飾する
+func paint(style, text string) string {
+	if style == "" {
+		return text
+	}
+	return "\033[" + style + "m" + text + "\033[0m"
+}
+
+// sgr は style を開始するエスケープシーケンス（"" ならリセット）
+func sgr(style string) string {
+	if style == "" {
+		return "\033[0m"
+	}
+	return "\033[0;" + style + "m"
+}
+
+// colorDepth は端末が表示できる色数
+type colorDepth int
+
+const (
+	color16 colorDepth = iota
+	color256
+	colorTrue
+)
+
+// detectColorDepth は環境変数から色数を推定する
+func detectColorDepth() colorDepth {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return colorTrue
+	}
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return color256
+	}
+	return color16
+}
+
+// noColorRequested は NO_COLOR（https://no-color.org/）が指定されているか
+func noColorRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// styleAttributes は色ではない装飾
+var styleAttributes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"reverse":   "7",
+}
+
+// colorNames は16色の名前（番号は 0〜15）
+var colorNames = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"bright-black", "bright-red", "bright-green", "bright-yellow",
+	"bright-blue", "bright-magenta", "bright-cyan", "bright-white",
+}
+
+// stripColors は配色の指定から色を除き装飾だけにする
+func stripColors(spec string) string {
+	var attrs []string
+	for _, token := range strings.Fields(spec) {
+		if _, ok := styleAttributes[token]; ok {
+			attrs = append(attrs, token)
+		}
+	}
+	return strings.Join(attrs, " ")
+}
+
+// parseStyle は配色の指定を SGR パラメータに変換する
+// 端末が対応していない色は depth に合わせて近い色に落とす
+func parseStyle(spec string, depth colorDepth) (string, error) {
+	var params []string
+	for _, token := range strings.Fields(spec) {
+		if attr, ok := styleAttributes[token]; ok {
+			params = append(params, attr)
+			continue
+		}
+
+		background := false
+		if rest, ok := strings.CutPrefix(token, "bg:"); ok {
+			token, background = rest, true
+		}
+		param, err := colorParam(token, background, depth)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, param)
+	}
+	return strings.Join(params, ";"), nil
+}
+
+// colorParam は色1つ分の SGR パラメータ
+func colorParam(token string, background bool, depth colorDepth) (string, error) {
+	index := -1
+	for i, name := range colorNames {
+		if token == name {
+			index = i
+		}
+	}
+
+	switch {
+	case index >= 0:
+		// 名前付きの16色はそのまま
+	case strings.HasPrefix(token, "#"):
+		rgb, err := strconv.ParseUint(token[1:], 16, 32)
+		if err != nil || len(token) != 7 {
+			return "", fmt.Errorf("invalid color %q (#rrggbb)", token)
+		}
+		r, g, b := int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)
+		if depth == colorTrue {
+			return fmt.Sprintf("%d;2;%d;%d;%d", extendedParam(background), r, g, b), nil
+		}
+		index = nearest256(r, g, b)
+	default:
+		n, err := strconv.Atoi(token)
+		if err != nil || n < 0 || n > 255 {
+			return "", fmt.Errorf("unknown color or attribute %q", token)
+		}
+		index = n
+	}
+
+	if index >= 16 {
+		if depth != color16 {
+			return fmt.Sprintf("%d;5;%d", extendedParam(background), index), nil
+		}
+		r, g, b := paletteRGB(index)
+		index = nearest16(r, g, b)
+	}
+	return basicParam(index, background), nil
+}
+
+// basicParam は16色の SGR パラメータ（30〜37, 90〜97、背景は +10）
+func basicParam(index int, background bool) string {
+	base := 30
+	if index >= 8 {
+		base, index = 90, index-8
+	}
+	if background {
+		base += 10
+	}
+	return strconv.Itoa(base + index)
+}
+
+// extendedParam は256色/フルカラー指定の先頭パラメータ（前景 38、背景 48）
+func extendedParam(background bool) int {
+	if background {
+		return 48
+	}
+	return 38
+}
+
+// cubeLevels は256色パレットの 6x6x6 色立方体の各段階の明るさ
+var cubeLevels = []int{0, 95, 135, 175, 215, 255}
+
+// basicRGB は16色の代表的な RGB 値（xterm の既定値）
+var basicRGB = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// paletteRGB は256色パレット番号の RGB 値
+func paletteRGB(index int) (int, int, int) {
+	switch {
+	case index < 16:
+		c := basicRGB[index]
+		return c[0], c[1], c[2]
+	case index < 232:
+		i := index - 16
+		return cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]
+	}
+	gray := 8 + (index-232)*10
+	return gray, gray, gray
+}
+
+// nearest256 は RGB に最も近い256色パレット番号（16〜255 から選ぶ）
+func nearest256(r, g, b int) int {
+	return nearestColor(r, g, b, 16, 256)
+}
+
+// nearest16 は RGB に最も近い16色の番号
+func nearest16(r, g, b int) int {
+	return nearestColor(r, g, b, 0, 16)
+}
+
+func nearestColor(r, g, b, from, to int) int {
+	best, bestDist := from, -1
+	for i := from; i < to; i++ {
+		pr, pg, pb := paletteRGB(i)
+		dist := (r-pr)*(r-pr) + (g-pg)*(g-pg) + (b-pb)*(b-pb)
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// LSColors は LS_COLORS の指定（種類ごとと拡張子ごとの SGR パラメータ）
+type LSColors struct {
+	types    map[string]string // "di" "ln" "ex" など
+	patterns map[string]string // "*.tar.gz" の ".tar.gz" 部分
+}
+
+// ParseLSColors は "di=01;34:ln=01;36:*.tar=01;31" 形式を解釈する
+func ParseLSColors(s string) LSColors {
+	lc := LSColors{types: map[string]string{}, patterns: map[string]string{}}
+	for _, item := range strings.Split(s, ":") {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || value == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(key, "*"); ok {
+			lc.patterns[strings.ToLower(suffix)] = value
+		} else {
+			lc.types[key] = value
+		}
+	}
+	return lc
+}
+
+// Style はエントリの SGR パラメータ（指定がなければ ""）
+func (lc LSColors) Style(entry FileEntry) string {
+	if len(lc.types) == 0 && len(lc.patterns) == 0 {
+		return ""
+	}
+
+	mode := entry.Mode
+	switch {
+	case entry.IsDir:
+		return lc.types["di"]
+	case mode&os.ModeSymlink != 0:
+		return lc.types["ln"]
+	case mode&os.ModeNamedPipe != 0:
+		return lc.types["pi"]
+	case mode&os.ModeSocket != 0:
+		return lc.types["so"]
+	case mode&os.ModeCharDevice != 0:
+		return lc.types["cd"]
+	case mode&os.ModeDevice != 0:
+		return lc.types["bd"]
+	case mode&0111 != 0 && lc.types["ex"] != "":
+		return lc.types["ex"]
+	}
+
+	// "*.tar.gz" と "*.gz" なら長い方を優先
+	name := strings.ToLower(filepath.Base(entry.Path))
+	style, matched := "", 0
+	for suffix, value := range lc.patterns {
+		if len(suffix) > matched && strings.HasSuffix(name, suffix) {
+			style, matched = value, len(suffix)
+		}
+	}
+	if style != "" {
+		return style
+	}
+	return lc.types["fi"]
+}
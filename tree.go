@@ -0,0 +1,190 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// treeNode はツリー表示での1行分の情報（filteredEntries と同じ並び）
+type treeNode struct {
+	depth    int
+	guide    string // "│   ├── " のような罫線
+	expanded bool   // ディレクトリの子を表示している
+	context  bool   // クエリには一致せず、一致したエントリの祖先として出している
+}
+
+const (
+	guideBranch = "├── "
+	guideLast   = "└── "
+	guidePipe   = "│   "
+	guideSpace  = "    "
+)
+
+// buildTree は filteredEntries をツリー順に並べ替え、treeNodes を作る
+// 絞り込み中は一致したエントリとその祖先をすべて展開して出し、
+// 絞り込みなしなら展開したディレクトリの子だけを出す
+func (m *Model) buildTree(filtering bool) {
+	// 一致したエントリ（「もしかして」候補はツリーには出さない）
+	matched := make(map[string]ScoredEntry)
+	for _, scored := range m.filteredEntries {
+		if !scored.Suggested {
+			matched[scored.Entry.Path] = scored
+		}
+	}
+
+	// 絞り込み中は一致したエントリの祖先も表示する
+	var visible map[string]bool
+	if filtering {
+		visible = make(map[string]bool)
+		for path := range matched {
+			for p := path; p != "." && !visible[p]; p = filepath.Dir(p) {
+				visible[p] = true
+			}
+		}
+	}
+
+	children := make(map[string][]FileEntry)
+	for _, entry := range m.allEntries {
+		if visible == nil || visible[entry.Path] {
+			children[entry.DirPath] = append(children[entry.DirPath], entry)
+		}
+	}
+
+	var entries []ScoredEntry
+	var nodes []treeNode
+	var walk func(dir, prefix string, depth int)
+	walk = func(dir, prefix string, depth int) {
+		kids := children[dir]
+		m.sortSiblings(kids)
+		for i, entry := range kids {
+			last := i == len(kids)-1
+			guide, next := guideBranch, guidePipe
+			if last {
+				guide, next = guideLast, guideSpace
+			}
+
+			scored, ok := matched[entry.Path]
+			if !ok {
+				scored = ScoredEntry{Entry: entry}
+			}
+			expanded := entry.IsDir && (filtering || m.expanded[entry.Path])
+			entries = append(entries, scored)
+			nodes = append(nodes, treeNode{
+				depth:    depth,
+				guide:    prefix + guide,
+				expanded: expanded,
+				context:  filtering && !ok,
+			})
+
+			if expanded {
+				walk(entry.Path, prefix+next, depth+1)
+			}
+		}
+	}
+	walk(".", "", 0)
+
+	m.filteredEntries = entries
+	m.treeNodes = nodes
+}
+
+// sortSiblings は同じディレクトリのエントリを並べる（ディレクトリが先、次に並び順、名前）
+func (m *Model) sortSiblings(entries []FileEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		if c := m.sortOrder.compare(a, b); c != 0 {
+			return c < 0
+		}
+		return compareNames(a.Name, b.Name) < 0
+	})
+}
+
+// toggleTree はフラットな一覧とツリー表示を切り替える（カーソルのエントリは保つ）
+func (m *Model) toggleTree() {
+	m.tree = !m.tree
+	m.columns = false
+	m.treeNodes = nil
+	m.refreshKeepingCursor()
+}
+
+// syncTree は不正なクエリで一覧を作り直せなかったとき、前回の結果に treeNodes を合わせる
+// （ツリー表示に切り替えた直後は filteredEntries がフラットな一覧のままなので）
+func (m *Model) syncTree() {
+	if !m.tree {
+		m.treeNodes = nil
+		return
+	}
+	if len(m.treeNodes) != len(m.filteredEntries) {
+		m.buildTree(true)
+		m.cursor = max(0, min(m.cursor, len(m.filteredEntries)-1))
+	}
+}
+
+// expandTree はカーソルのディレクトリを展開する（展開済みなら最初の子へ）
+func (m *Model) expandTree() {
+	if len(m.filteredEntries) == 0 {
+		return
+	}
+	entry := m.filteredEntries[m.cursor].Entry
+	if !entry.IsDir {
+		return
+	}
+	if m.treeNodes[m.cursor].expanded {
+		if m.cursor+1 < len(m.treeNodes) && m.treeNodes[m.cursor+1].depth > m.treeNodes[m.cursor].depth {
+			m.moveCursorTo(m.cursor + 1)
+		}
+		return
+	}
+	m.expanded[entry.Path] = true
+	m.refreshKeepingCursor()
+}
+
+// collapseTree はカーソルのディレクトリを折りたたむ（折りたたみ済みやファイルなら親へ）
+func (m *Model) collapseTree() {
+	if len(m.filteredEntries) == 0 {
+		return
+	}
+	entry := m.filteredEntries[m.cursor].Entry
+	if entry.IsDir && m.treeNodes[m.cursor].expanded {
+		delete(m.expanded, entry.Path)
+		m.refreshKeepingCursor()
+		return
+	}
+	for i, scored := range m.filteredEntries {
+		if scored.Entry.Path == entry.DirPath {
+			m.moveCursorTo(i)
+			return
+		}
+	}
+}
+
+// refreshKeepingCursor は一覧を作り直し、カーソルを同じエントリに戻す
+func (m *Model) refreshKeepingCursor() {
+	path := ""
+	if m.cursor < len(m.filteredEntries) {
+		path = m.filteredEntries[m.cursor].Entry.Path
+	}
+	m.updateFilter()
+	for i, scored := range m.filteredEntries {
+		if scored.Entry.Path == path {
+			m.moveCursorTo(i)
+			break
+		}
+	}
+}
+
+// shiftSpans はハイライト範囲を offset バイト前にずらす（範囲外は切り捨て）
+// Path 上の範囲をファイル名だけの表示に合わせるときに使う
+func shiftSpans(spans [][]int, offset int) [][]int {
+	var shifted [][]int
+	for _, span := range spans {
+		start, end := span[0]-offset, span[1]-offset
+		if end <= 0 {
+			continue
+		}
+		shifted = append(shifted, []int{max(0, start), end})
+	}
+	return shifted
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestToggleTreeWithInvalidQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		regex bool
+	}{
+		{"不正な条件", "main size:>abc", false},
+		{"不正な正規表現", "(", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel("cmd/", "cmd/app/", "cmd/app/main.go", "cmd/tool/", "cmd/tool/main.go", "README.md")
+			m.regexMode = tt.regex
+			m.query = tt.query
+			m.updateFilter()
+			if m.queryErr == "" {
+				t.Fatalf("%q: expected a query error", tt.query)
+			}
+
+			// 前回の結果のままツリー表示に切り替えても描画できる
+			m.toggleTree()
+			if len(m.treeNodes) != len(m.filteredEntries) {
+				t.Fatalf("treeNodes = %d, filteredEntries = %d", len(m.treeNodes), len(m.filteredEntries))
+			}
+			if view := m.View(); !strings.Contains(view, "main.go") {
+				t.Errorf("tree view does not show the entries:\n%s", view)
+			}
+
+			m.toggleTree()
+			if m.treeNodes != nil {
+				t.Errorf("treeNodes should be cleared in the flat list")
+			}
+			m.View()
+		})
+	}
+}
+
+func TestBuildTreeShowsAncestorsOfMatches(t *testing.T) {
+	m := newTestModel("cmd/", "cmd/app/", "cmd/app/main.go", "cmd/tool/", "cmd/tool/run.go", "internal/", "internal/render.go")
+	m.toggleTree()
+	if got := strings.Join(m.paths(), " "); got != "cmd internal" {
+		t.Errorf("collapsed tree = %q, want the top-level directories", got)
+	}
+
+	m.query = "main"
+	m.updateFilter()
+	if got := strings.Join(m.paths(), " "); got != "cmd cmd/app cmd/app/main.go" {
+		t.Errorf("filtered tree = %q, want main.go and its ancestors", got)
+	}
+	if !m.treeNodes[0].context || m.treeNodes[2].context {
+		t.Errorf("ancestors should be marked as context, matches should not: %+v", m.treeNodes)
+	}
+}
+
+func TestTreeRowsFitListWidth(t *testing.T) {
+	// 10階層のツリーを右プレビュー付きの 80x24 で表示する
+	var paths []string
+	dir := ""
+	for i := range 10 {
+		dir += fmt.Sprintf("directory%d/", i)
+		paths = append(paths, dir)
+	}
+	paths = append(paths, dir+"a_rather_long_file_name_at_the_bottom.go")
+
+	m := newTestModel(paths...)
+	m.config.EnablePreview = true
+	m.config.Layout.PreviewPosition = "right"
+	m.SetLayout(m.config.Layout)
+	m.query = "bottom"
+	m.toggleTree()
+
+	width := m.listWidth()
+	for _, line := range m.renderList(width, m.listHeight()) {
+		if n := visibleLength(line); n > width {
+			t.Errorf("row is %d wide, listWidth is %d: %q", n, width, line)
+		}
+	}
+
+	// "..." すら入らない幅でもはみ出さない
+	for w := range 8 {
+		for i := range m.filteredEntries {
+			if n := visibleLength(m.entryLine(i, w)); n > w {
+				t.Errorf("entryLine(%d, %d) is %d wide", i, w, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRanges は East Asian Width が W/F の主な範囲（全角2文字幅）
+// runeWidth で先頭から順に見るので昇順に並べること
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // ハングル字母
+	{0x231A, 0x231B},   // ⌚⌛
+	{0x23E9, 0x23EC},   // ⏩〜⏬
+	{0x23F0, 0x23F0},   // ⏰
+	{0x23F3, 0x23F3},   // ⏳
+	{0x25FD, 0x25FE},   // ◽◾
+	{0x2614, 0x2615},   // ☔☕
+	{0x2648, 0x2653},   // 星座
+	{0x267F, 0x267F},   // ♿
+	{0x2693, 0x2693},   // ⚓
+	{0x26A1, 0x26A1},   // ⚡
+	{0x26AA, 0x26AB},   // ⚪⚫
+	{0x26BD, 0x26BE},   // ⚽⚾
+	{0x26C4, 0x26C5},   // ⛄⛅
+	{0x26CE, 0x26CE},   // ⛎
+	{0x26D4, 0x26D4},   // ⛔
+	{0x26EA, 0x26EA},   // ⛪
+	{0x26F2, 0x26F3},   // ⛲⛳
+	{0x26F5, 0x26F5},   // ⛵
+	{0x26FA, 0x26FA},   // ⛺
+	{0x26FD, 0x26FD},   // ⛽
+	{0x2705, 0x2705},   // ✅
+	{0x270A, 0x270B},   // ✊✋
+	{0x2728, 0x2728},   // ✨
+	{0x274C, 0x274C},   // ❌
+	{0x274E, 0x274E},   // ❎
+	{0x2753, 0x2755},   // ❓❔❕
+	{0x2757, 0x2757},   // ❗
+	{0x2795, 0x2797},   // ➕➖➗
+	{0x27B0, 0x27B0},   // ➰
+	{0x27BF, 0x27BF},   // ➿
+	{0x2B1B, 0x2B1C},   // ⬛⬜
+	{0x2B50, 0x2B50},   // ⭐
+	{0x2B55, 0x2B55},   // ⭕
+	{0x2E80, 0x303E},   // CJK部首、記号
+	{0x3041, 0x33FF},   // ひらがな、カタカナ、CJK互換
+	{0x3400, 0x4DBF},   // CJK統合漢字拡張A
+	{0x4E00, 0x9FFF},   // CJK統合漢字
+	{0xA000, 0xA4CF},   // イ文字
+	{0xA960, 0xA97F},   // ハングル字母拡張A
+	{0xAC00, 0xD7A3},   // ハングル音節
+	{0xF900, 0xFAFF},   // CJK互換漢字
+	{0xFE10, 0xFE19},   // 縦書き形
+	{0xFE30, 0xFE6F},   // CJK互換形、小字形
+	{0xFF00, 0xFF60},   // 全角英数記号
+	{0xFFE0, 0xFFE6},   // 全角記号
+	{0x16FE0, 0x18AFF}, // 西夏文字など
+	{0x1B000, 0x1B2FF}, // 仮名補助
+	{0x1F004, 0x1F004}, // 🀄
+	{0x1F0CF, 0x1F0CF}, // 🃏
+	{0x1F18E, 0x1F18E}, // 🆎
+	{0x1F191, 0x1F19A}, // 🆑〜🆚
+	{0x1F200, 0x1F251}, // 囲み漢字
+	{0x1F300, 0x1F64F}, // 絵文字
+	{0x1F680, 0x1F6FF}, // 乗り物、地図記号
+	{0x1F7E0, 0x1F7EB}, // 色付きの丸と四角
+	{0x1F900, 0x1F9FF}, // 補助絵文字
+	{0x1FA70, 0x1FAFF}, // 絵文字拡張A
+	{0x20000, 0x2FFFD}, // CJK統合漢字拡張B以降
+	{0x30000, 0x3FFFD},
+}
+
+const (
+	zeroWidthJoiner   = 0x200D
+	emojiPresentation = 0xFE0F // 絵文字として表示（2文字幅）
+	ellipsis          = "..."
+)
+
+// runeWidth は文字単体の表示幅（0, 1, 2）
+func runeWidth(r rune) int {
+	switch {
+	case r == zeroWidthJoiner || (r >= 0xFE00 && r <= 0xFE0F):
+		// ゼロ幅接合子、異体字セレクタ
+		return 0
+	case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.IsControl(r):
+		return 0
+	}
+
+	for _, rng := range wideRanges {
+		if r < rng[0] {
+			break
+		}
+		if r <= rng[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// isGraphemeExtend は直前の文字にくっつく文字か（結合文字、異体字セレクタ、肌色修飾など）
+func isGraphemeExtend(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Mc, r) ||
+		(r >= 0xFE00 && r <= 0xFE0F) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		(r >= 0xE0020 && r <= 0xE007F)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// graphemes は文字列を書記素クラスタ（見た目の1文字）に分割する
+// 結合文字、ZWJ 連結の絵文字、国旗（地域指示子のペア）を1つにまとめる簡易版
+func graphemes(s string) []string {
+	runes := []rune(s)
+	var clusters []string
+
+	for i := 0; i < len(runes); {
+		start := i
+		i++
+		for i < len(runes) {
+			r := runes[i]
+			switch {
+			case isGraphemeExtend(r):
+				i++
+			case r == zeroWidthJoiner:
+				// ZWJ の次の文字まで同じクラスタ
+				i = min(i+2, len(runes))
+			case isRegionalIndicator(r) && i-start == 1 && isRegionalIndicator(runes[start]):
+				i++
+			default:
+				goto done
+			}
+		}
+	done:
+		clusters = append(clusters, string(runes[start:i]))
+	}
+
+	return clusters
+}
+
+// clusterWidth は書記素クラスタの表示幅
+func clusterWidth(g string) int {
+	runes := []rune(g)
+	if len(runes) == 0 {
+		return 0
+	}
+	if len(runes) == 1 {
+		return runeWidth(runes[0])
+	}
+	if isRegionalIndicator(runes[0]) {
+		return 2 // 国旗
+	}
+	for _, r := range runes {
+		if r == emojiPresentation {
+			return 2
+		}
+	}
+	return runeWidth(runes[0])
+}
+
+// stringWidth は文字列の表示幅（エスケープシーケンスを含まないこと）
+func stringWidth(s string) int {
+	n := 0
+	for _, g := range graphemes(s) {
+		n += clusterWidth(g)
+	}
+	return n
+}
+
+// truncatePrefix は表示幅 width に収まる先頭部分（書記素クラスタの途中では切らない）
+func truncatePrefix(s string, width int) string {
+	n := 0
+	var b strings.Builder
+	for _, g := range graphemes(s) {
+		w := clusterWidth(g)
+		if n+w > width {
+			break
+		}
+		n += w
+		b.WriteString(g)
+	}
+	return b.String()
+}
+
+// truncateWidth は表示幅 width を超える場合に末尾を "..." にして切り詰める
+func truncateWidth(s string, width int) string {
+	if stringWidth(s) <= width {
+		return s
+	}
+	if width < len(ellipsis) {
+		return truncatePrefix(s, width)
+	}
+	return truncatePrefix(s, width-len(ellipsis)) + ellipsis
+}
+
+// ansiSegment はエスケープシーケンスか通常の文字列の断片
+type ansiSegment struct {
+	text   string
+	escape bool
+}
+
+// splitANSI は文字列をエスケープシーケンス（CSI）と通常の文字列に分ける
+func splitANSI(s string) []ansiSegment {
+	var segments []ansiSegment
+	textStart := 0
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\033' {
+			continue
+		}
+		if i > textStart {
+			segments = append(segments, ansiSegment{text: s[textStart:i]})
+		}
+
+		// ESC [ ... 終端文字（0x40〜0x7E）
+		j := i + 1
+		if j < len(s) && s[j] == '[' {
+			j++
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+		}
+		j = min(j+1, len(s))
+		segments = append(segments, ansiSegment{text: s[i:j], escape: true})
+		i = j - 1
+		textStart = j
+	}
+
+	if textStart < len(s) {
+		segments = append(segments, ansiSegment{text: s[textStart:]})
+	}
+	return segments
+}
+
+// visibleLength はエスケープシーケンスを除いた表示幅
+func visibleLength(s string) int {
+	n := 0
+	for _, seg := range splitANSI(s) {
+		if !seg.escape {
+			n += stringWidth(seg.text)
+		}
+	}
+	return n
+}
+
+// padRight は表示幅 width まで空白で埋める
+func padRight(s string, width int) string {
+	return s + strings.Repeat(" ", max(0, width-visibleLength(s)))
+}
+
+// truncateANSI はエスケープシーケンスを保ったまま表示幅 width に切り詰める
+func truncateANSI(s string, width int) string {
+	var b strings.Builder
+	n := 0
+	for _, seg := range splitANSI(s) {
+		if seg.escape {
+			b.WriteString(seg.text)
+			continue
+		}
+		for _, g := range graphemes(seg.text) {
+			w := clusterWidth(g)
+			if n+w > width {
+				b.WriteString("\033[0m")
+				return b.String()
+			}
+			n += w
+			b.WriteString(g)
+		}
+	}
+	return b.String()
+}